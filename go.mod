@@ -3,6 +3,16 @@ module github.com/jcocozza/jbf
 go 1.23.4
 
 require (
-	github.com/mattn/go-sqlite3 v1.14.24 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	github.com/BurntSushi/toml v1.6.0
+	github.com/HugoSmits86/nativewebp v1.2.0
+	github.com/mattn/go-sqlite3 v1.14.24
+	github.com/tdewolff/minify/v2 v2.20.37
+	golang.org/x/image v0.30.0
+	golang.org/x/net v0.38.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/tdewolff/parse/v2 v2.7.15 // indirect
+	golang.org/x/text v0.28.0 // indirect
 )
@@ -0,0 +1,136 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jcocozza/jbf/internal/fsutil"
+	"github.com/tdewolff/minify/v2"
+	"github.com/tdewolff/minify/v2/css"
+	"github.com/tdewolff/minify/v2/html"
+	"github.com/tdewolff/minify/v2/js"
+)
+
+// Bundle is a file made by joining static files together
+type Bundle struct {
+	// the name of the bundle in the static files, e.g. site.css
+	Name string `yaml:"name"`
+	// the static files in the bundle, in order
+	Files []string `yaml:"files"`
+}
+
+// Options controls how static files are written
+type Options struct {
+	// minify css and js files
+	Minify bool
+	// also write each file under a name with a hash of its content, e.g. styles.3f2a9c1b.css
+	Fingerprint bool
+	Bundles     []Bundle
+}
+
+// Manifest is the url of each static file, by its name in the static files
+type Manifest map[string]string
+
+// URL is the url of the static file with the name, e.g. styles.css is /static/styles.3f2a9c1b.css.
+// files that are not in the manifest are at their usual url.
+func (m Manifest) URL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if u, ok := m[name]; ok {
+		return u
+	}
+	return "/static/" + name
+}
+
+var minifier = newMinifier()
+
+func newMinifier() *minify.M {
+	m := minify.New()
+	m.AddFunc("text/css", css.Minify)
+	m.AddFunc("text/javascript", js.Minify)
+	m.AddFuncRegexp(regexp.MustCompile("^(application|text)/(x-)?(java|ecma)script$"), js.Minify)
+	m.Add("text/html", &html.Minifier{KeepDocumentTags: true, KeepEndTags: true, KeepQuotes: true})
+	return m
+}
+
+// mediaTypes are the static files that can be minified, by extension
+var mediaTypes = map[string]string{
+	".css": "text/css",
+	".js":  "text/javascript",
+}
+
+// MinifyHTML minifies a rendered page
+func MinifyHTML(page string) (string, error) {
+	return minifier.String("text/html", page)
+}
+
+func fingerprinted(name string, content []byte) string {
+	sum := sha256.Sum256(content)
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:])[:8] + ext
+}
+
+/*
+Build writes the static files and bundles to the output dir under /static, minified and fingerprinted if enabled.
+files are always also written under their own name so that fixed paths like /static/styles.css keep working.
+*/
+func Build(static fs.FS, outputDir string, opts Options) (Manifest, error) {
+	manifest := Manifest{}
+	write := func(name string, content []byte) error {
+		if mediaType, ok := mediaTypes[path.Ext(name)]; ok && opts.Minify {
+			minified, err := minifier.Bytes(mediaType, content)
+			if err != nil {
+				return fmt.Errorf("unable to minify %s: %w", name, err)
+			}
+			content = minified
+		}
+		if err := fsutil.WriteReadOnly(filepath.Join(outputDir, "static", filepath.FromSlash(name)), content); err != nil {
+			return err
+		}
+		manifest[name] = "/static/" + name
+		if opts.Fingerprint {
+			hashed := fingerprinted(name, content)
+			if err := fsutil.WriteReadOnly(filepath.Join(outputDir, "static", filepath.FromSlash(hashed)), content); err != nil {
+				return err
+			}
+			manifest[name] = "/static/" + hashed
+		}
+		return nil
+	}
+	err := fs.WalkDir(static, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(static, name)
+		if err != nil {
+			return err
+		}
+		return write(name, content)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range opts.Bundles {
+		if _, ok := manifest[b.Name]; ok {
+			return nil, fmt.Errorf("bundle %s has the same name as a static file", b.Name)
+		}
+		var joined []byte
+		for _, f := range b.Files {
+			content, err := fs.ReadFile(static, strings.TrimPrefix(f, "/"))
+			if err != nil {
+				return nil, fmt.Errorf("unable to bundle %s: %w", b.Name, err)
+			}
+			joined = append(joined, content...)
+			joined = append(joined, '\n')
+		}
+		if err := write(b.Name, joined); err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
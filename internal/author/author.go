@@ -0,0 +1,125 @@
+package author
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jcocozza/jbf/internal/slug"
+	"gopkg.in/yaml.v3"
+)
+
+const DefaultPath string = "authors.yaml"
+
+const authorsURL = "/authors/"
+
+type Link struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+}
+
+// Author is a profile from the authors file
+type Author struct {
+	// the key of the author in the authors file
+	Slug   string `yaml:"-"`
+	Name   string `yaml:"name"`
+	Bio    string `yaml:"bio"`
+	Avatar string `yaml:"avatar"`
+	Links  []Link `yaml:"links"`
+}
+
+// URL is the root relative url of the author page
+func (a Author) URL() string {
+	return URL(a.Slug)
+}
+
+func URL(authorSlug string) string {
+	return authorsURL + authorSlug + "/"
+}
+
+// IndexURL is the url of the page listing every author
+func IndexURL() string {
+	return authorsURL
+}
+
+/*
+Registry is the set of authors defined in the authors file. e.g.
+
+	jdoe:
+	  name: Jane Doe
+	  bio: writes about databases
+	  avatar: /static/jdoe.png
+	  links:
+	    - name: github
+	      url: https://github.com/jdoe
+*/
+type Registry map[string]Author
+
+// Load reads the authors file. A missing file is an empty registry.
+func Load(path string) (Registry, error) {
+	r := Registry{}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(content, &r); err != nil {
+		return nil, fmt.Errorf("unable to parse authors file %s: %w", path, err)
+	}
+	byName := map[string]string{}
+	for _, key := range r.keys() {
+		a := r[key]
+		a.Slug = key
+		if a.Name == "" {
+			a.Name = key
+		}
+		r[key] = a
+		// front matter can refer to an author by name, so names have to be unique
+		if other, ok := byName[strings.ToLower(a.Name)]; ok {
+			return nil, fmt.Errorf("authors %s and %s in %s have the same name %q", other, key, path, a.Name)
+		}
+		byName[strings.ToLower(a.Name)] = key
+	}
+	return r, nil
+}
+
+// keys are the keys of the authors in sorted order
+func (r Registry) keys() []string {
+	keys := make([]string, 0, len(r))
+	for key := range r {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/*
+Resolve finds the author that a front matter author refers to, by key or by display name.
+authors that are not in the registry get a profile with just their name.
+*/
+func (r Registry) Resolve(name string) Author {
+	if a, ok := r[name]; ok {
+		return a
+	}
+	for _, key := range r.keys() {
+		if strings.EqualFold(r[key].Name, name) {
+			return r[key]
+		}
+	}
+	if a, ok := r[slug.Path(name)]; ok {
+		return a
+	}
+	return Author{Slug: slug.Path(name), Name: name}
+}
+
+// ResolveAll resolves each of the names
+func (r Registry) ResolveAll(names []string) []Author {
+	authors := make([]Author, 0, len(names))
+	for _, name := range names {
+		authors = append(authors, r.Resolve(name))
+	}
+	return authors
+}
@@ -0,0 +1,245 @@
+package cards
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jcocozza/jbf/internal/fsutil"
+	"github.com/jcocozza/jbf/internal/slug"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// the url the cards are written under
+const cardsURL = "/static/og/"
+
+// the size of a card, the size link previews expect
+const (
+	Width  = 1200
+	Height = 630
+)
+
+const (
+	margin = 80
+	// the title is drawn at the first of these sizes that fits in maxLines
+	maxLines = 4
+)
+
+var titleSizes = []float64{72, 60, 48}
+
+// design changes whenever the way cards are drawn does, so that cached cards are drawn again
+const design = 1
+
+var (
+	background = color.RGBA{0x0d, 0x11, 0x17, 0xff}
+	foreground = color.RGBA{0xe6, 0xed, 0xf3, 0xff}
+	muted      = color.RGBA{0x91, 0x98, 0xa1, 0xff}
+	// the default accent of the theme
+	defaultAccent = color.RGBA{0x25, 0x63, 0xeb, 0xff}
+)
+
+// Options controls how the cards look and where they are kept
+type Options struct {
+	SiteName string
+	// the color of the bar along the top of the card. colors that are not #rgb or #rrggbb use the theme's accent
+	Accent string
+	// cards are kept here between builds so a card is only drawn again when what is on it changes
+	CacheDir string
+}
+
+// Generator draws the link preview cards of pages and writes them to the output dir
+type Generator struct {
+	opts      Options
+	outputDir string
+	accent    color.RGBA
+	bold      *opentype.Font
+	regular   *opentype.Font
+}
+
+// New makes a generator that writes cards to /static/og/ in outputDir
+func New(opts Options, outputDir string) (*Generator, error) {
+	bold, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return nil, err
+	}
+	regular, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, err
+	}
+	accent, ok := parseHex(opts.Accent)
+	if !ok {
+		accent = defaultAccent
+	}
+	return &Generator{opts: opts, outputDir: outputDir, accent: accent, bold: bold, regular: regular}, nil
+}
+
+// parseHex reads a #rgb or #rrggbb color
+func parseHex(s string) (color.RGBA, bool) {
+	digits, ok := strings.CutPrefix(strings.TrimSpace(s), "#")
+	if !ok {
+		return color.RGBA{}, false
+	}
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+	v, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || len(digits) != 6 {
+		return color.RGBA{}, false
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}, true
+}
+
+/*
+Card writes the card of the page at pageURL to the output dir and returns its url.
+date is shown as given and can be empty.
+the url has a hash of the page url and of what is on the card in it (the title, date, site name and accent),
+so pages with the same title get cards of their own and a changed card gets a new url and previews are not stale.
+*/
+func (g *Generator) Card(pageURL string, title string, date string) (string, error) {
+	// everything drawn on the card is part of the hash, along with the version of the design
+	h := sha256.New()
+	fmt.Fprintf(h, "v%d\x00%s\x00%s\x00%s\x00%s\x00%v", design, pageURL, title, date, g.opts.SiteName, g.accent)
+	name := strings.Trim(strings.TrimSuffix(pageURL, ".html"), "/")
+	if name == "" {
+		name = "index"
+	}
+	file := fmt.Sprintf("%s-%s.png", slug.Make(name), hex.EncodeToString(h.Sum(nil))[:12])
+	dest := filepath.Join(g.outputDir, filepath.FromSlash(cardsURL), file)
+	if _, err := os.Stat(dest); err == nil {
+		// written by an earlier compile into the same output dir
+		return cardsURL + file, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", err
+	}
+	cached := ""
+	if g.opts.CacheDir != "" {
+		cached = filepath.Join(g.opts.CacheDir, file)
+		if content, err := os.ReadFile(cached); err == nil {
+			return cardsURL + file, fsutil.WriteReadOnly(dest, content)
+		}
+	}
+	content, err := g.draw(title, date)
+	if err != nil {
+		return "", err
+	}
+	if cached != "" {
+		if err := os.MkdirAll(g.opts.CacheDir, 0755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(cached, content, 0644); err != nil {
+			return "", err
+		}
+	}
+	return cardsURL + file, fsutil.WriteReadOnly(dest, content)
+}
+
+// draw renders the card as a png
+func (g *Generator) draw(title string, date string) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, Width, 16), image.NewUniform(g.accent), image.Point{}, draw.Src)
+
+	titleFace, lines, err := g.fitTitle(title)
+	if err != nil {
+		return nil, err
+	}
+	defer titleFace.Close()
+	lineHeight := titleFace.Metrics().Height.Ceil() * 6 / 5
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(foreground), Face: titleFace}
+	y := margin + 16 + titleFace.Metrics().Ascent.Ceil()
+	for _, line := range lines {
+		d.Dot = fixed.P(margin, y)
+		d.DrawString(line)
+		y += lineHeight
+	}
+
+	footerFace, err := opentype.NewFace(g.regular, &opentype.FaceOptions{Size: 32, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+	defer footerFace.Close()
+	d = &font.Drawer{Dst: img, Src: image.NewUniform(muted), Face: footerFace}
+	d.Dot = fixed.P(margin, Height-margin)
+	d.DrawString(g.opts.SiteName)
+	if date != "" {
+		d.Dot = fixed.P(Width-margin-d.MeasureString(date).Ceil(), Height-margin)
+		d.DrawString(date)
+	}
+
+	var b bytes.Buffer
+	if err := (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&b, img); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// fitTitle picks the largest title size that fits in maxLines, cutting the title short at the smallest size
+func (g *Generator) fitTitle(title string) (font.Face, []string, error) {
+	for i, size := range titleSizes {
+		face, err := opentype.NewFace(g.bold, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			return nil, nil, err
+		}
+		lines := wrap(face, title, Width-2*margin)
+		if len(lines) <= maxLines {
+			return face, lines, nil
+		}
+		if i == len(titleSizes)-1 {
+			lines = lines[:maxLines]
+			lines[maxLines-1] = ellipsize(face, lines[maxLines-1], Width-2*margin)
+			return face, lines, nil
+		}
+		face.Close()
+	}
+	return nil, nil, fmt.Errorf("no title sizes")
+}
+
+// wrap splits text into lines no wider than width. words wider than a line get a line of their own
+func wrap(face font.Face, text string, width int) []string {
+	lines := []string{}
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if line != "" && font.MeasureString(face, candidate).Ceil() > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line = candidate
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// ellipsize adds an ellipsis to the end of line, dropping words until it fits in width
+func ellipsize(face font.Face, line string, width int) string {
+	for {
+		if font.MeasureString(face, line+"…").Ceil() <= width {
+			return line + "…"
+		}
+		i := strings.LastIndex(line, " ")
+		if i < 0 {
+			return line + "…"
+		}
+		line = line[:i]
+	}
+}
@@ -0,0 +1,159 @@
+package check
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/jcocozza/jbf/internal/config"
+	"github.com/jcocozza/jbf/internal/metadata"
+)
+
+// Problem is a single issue found in a content file
+type Problem struct {
+	File string
+	// 0 when the line is unknown
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s", p.File, p.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+}
+
+// Sort orders problems by file and then line
+func Sort(problems []Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		return problems[i].Line < problems[j].Line
+	})
+}
+
+// Metadata validates m against the built in rules and the user defined schema. schema dates without a timezone are read in loc
+func Metadata(m metadata.Metadata, schema config.Schema, loc *time.Location) []Problem {
+	problems := []Problem{}
+	report := func(key string, format string, a ...any) {
+		problems = append(problems, Problem{File: m.Filepath, Line: m.Line(key), Message: fmt.Sprintf(format, a...)})
+	}
+
+	// built in rules
+	if m.Title == "" {
+		report("title", "title is required")
+	}
+	if m.Has("author") && m.Author == "" {
+		report("author", "author is empty")
+	}
+	for _, a := range m.Authors {
+		if a == "" {
+			report("authors", "authors contains an empty author")
+		}
+	}
+	if !m.Created.IsZero() && !m.LastUpdated.IsZero() && m.LastUpdated.Before(m.Created) {
+		report("last_updated", "last_updated (%s) is before created (%s)", m.LastUpdated, m.Created)
+	}
+	for _, tag := range m.Tags {
+		if tag == "" {
+			report("tags", "tags contains an empty tag")
+		}
+	}
+
+	// user defined rules
+	for _, key := range schema.Required {
+		if !m.Has(key) {
+			report(key, "%s is required", key)
+		}
+	}
+	if len(schema.AllowedTags) > 0 {
+		for _, tag := range m.Tags {
+			if tag != "" && !slices.Contains(schema.AllowedTags, tag) {
+				report("tags", "tag %q is not one of the allowed tags %v", tag, schema.AllowedTags)
+			}
+		}
+	}
+	dates := []struct {
+		key  string
+		date metadata.Date
+		rng  config.DateRange
+	}{
+		{"created", m.Created, schema.Created},
+		{"last_updated", m.LastUpdated, schema.LastUpdated},
+	}
+	for _, d := range dates {
+		if d.date.IsZero() {
+			continue
+		}
+		if d.rng.After != "" {
+			after, err := metadata.ParseDate(d.rng.After, loc)
+			if err != nil {
+				report(d.key, "invalid schema date: %s", err)
+			} else if !after.Before(d.date) {
+				report(d.key, "%s (%s) must be after %s", d.key, d.date, after)
+			}
+		}
+		if d.rng.Before != "" {
+			before, err := metadata.ParseDate(d.rng.Before, loc)
+			if err != nil {
+				report(d.key, "invalid schema date: %s", err)
+			} else if !d.date.Before(before) {
+				report(d.key, "%s (%s) must be before %s", d.key, d.date, before)
+			}
+		}
+	}
+	return problems
+}
+
+// File validates the front matter of a content file, reading dates without a timezone in loc
+func File(path string, schema config.Schema, loc *time.Location) ([]Problem, error) {
+	m, err := metadata.ExtractFromFile(path, loc)
+	if err == nil {
+		return Metadata(m, schema, loc), nil
+	}
+	var perr *metadata.ParseError
+	if errors.As(err, &perr) {
+		return []Problem{{File: path, Line: perr.Line, Message: perr.Err.Error()}}, nil
+	}
+	if errors.Is(err, metadata.ErrNoMetadata) {
+		return []Problem{{File: path, Line: 1, Message: metadata.ErrNoMetadata.Error()}}, nil
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		// the file can't be read, which is not a problem with its content
+		return nil, err
+	}
+	// any other decode error, e.g. a toml date that can't be decoded, is reported on the first line
+	return []Problem{{File: path, Line: 1, Message: err.Error()}}, nil
+}
+
+// Content validates every content file in contentDir, reading dates without a timezone in loc
+func Content(contentDir string, schema config.Schema, loc *time.Location) ([]Problem, error) {
+	problems := []Problem{}
+	err := filepath.Walk(contentDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		p, err := File(path, schema, loc)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, p...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	Sort(problems)
+	return problems, nil
+}
@@ -0,0 +1,155 @@
+package check
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// the attributes that reference other files
+var linkAttributes = map[string]bool{
+	"href": true,
+	"src":  true,
+}
+
+// Links reports every href and src in the html files of outputDir that does not resolve to a file in outputDir.
+// sources maps an output file (relative to outputDir) to the content file it was compiled from.
+// routes are root relative paths that are served without a file (e.g. /all).
+func Links(outputDir string, sources map[string]string, routes []string) ([]Problem, error) {
+	problems := []Problem{}
+	err := filepath.Walk(outputDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(p) != ".html" {
+			return nil
+		}
+		rel, err := filepath.Rel(outputDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		refs, err := htmlReferences(p)
+		if err != nil {
+			return err
+		}
+		source, ok := sources[rel]
+		var content []string
+		if ok {
+			if b, err := os.ReadFile(source); err == nil {
+				content = strings.Split(string(b), "\n")
+			}
+		}
+		for _, ref := range refs {
+			if resolves(outputDir, rel, ref.value, routes) {
+				continue
+			}
+			problem := Problem{File: p, Line: ref.line, Message: fmt.Sprintf("broken link %q", ref.value)}
+			// links written in the content are reported where they are written, the rest (e.g. from the layout) in the html file they are in
+			if line := findLine(content, ref.value); line > 0 {
+				problem = Problem{File: source, Line: line, Message: fmt.Sprintf("broken link %q in %s", ref.value, rel)}
+			}
+			problems = append(problems, problem)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	Sort(problems)
+	return problems, nil
+}
+
+// reference is the value of a link attribute and the line of the html file it is on
+type reference struct {
+	value string
+	line  int
+}
+
+// htmlReferences returns the values of the link attributes of the html file
+func htmlReferences(p string) ([]reference, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	refs := []reference{}
+	z := html.NewTokenizer(f)
+	line := 1
+	for {
+		tt := z.Next()
+		start := line
+		line += strings.Count(string(z.Raw()), "\n")
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return refs, nil
+			}
+			return nil, z.Err()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			for _, attr := range z.Token().Attr {
+				if linkAttributes[attr.Key] {
+					refs = append(refs, reference{value: attr.Val, line: start})
+				}
+			}
+		}
+	}
+}
+
+// linkSyntax are the ways a link to a ref (%s) is written in markdown and html, up to the character that ends the ref
+var linkSyntax = []string{"](%s)", "](%s ", "]: %s ", "<%s>", `href="%s"`, `href='%s'`, `src="%s"`, `src='%s'`}
+
+// findLine is the 1 based line of the first of lines that links to ref, or 0 if none do.
+// only links count, so that e.g. the / of a layout link is not found in the text of the content
+func findLine(lines []string, ref string) int {
+	if ref == "" {
+		return 0
+	}
+	for i, l := range lines {
+		// a ref at the end of the line ends with the line
+		l += " "
+		for _, syntax := range linkSyntax {
+			if strings.Contains(l, fmt.Sprintf(syntax, ref)) {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// resolves reports whether ref, found in the output file page, points to something that exists
+func resolves(outputDir string, page string, ref string, routes []string) bool {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return false
+	}
+	// external links, mailto: and data: are not checked. neither are links to the page itself
+	if u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return true
+	}
+	target := u.Path
+	if !strings.HasPrefix(target, "/") {
+		target = path.Join("/", path.Dir(page), target)
+	}
+	target = path.Clean(target)
+	if slices.Contains(routes, target) {
+		return true
+	}
+	info, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(target)))
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		// directories are served by their index file
+		_, err = os.Stat(filepath.Join(outputDir, filepath.FromSlash(target), "index.html"))
+		return err == nil
+	}
+	return true
+}
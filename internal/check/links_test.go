@@ -0,0 +1,123 @@
+package check
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFiles writes files (by slash separated path) under dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResolves(t *testing.T) {
+	outputDir := t.TempDir()
+	writeFiles(t, outputDir, map[string]string{
+		"index.html":          "",
+		"blog/post.html":      "",
+		"blog/index.html":     "",
+		"notes/n1.html":       "",
+		"static/img/cat.png":  "",
+		"my notés/index.html": "",
+	})
+	tests := []struct {
+		name string
+		ref  string
+		want bool
+	}{
+		{"root", "/", true},
+		{"absolute", "/blog/post.html", true},
+		{"relative", "post.html", true},
+		{"parent", "../notes/n1.html", true},
+		{"fragment", "/blog/post.html#intro", true},
+		{"query", "/blog/post.html?x=1", true},
+		{"same page fragment", "#intro", true},
+		{"external", "https://example.com/missing", true},
+		{"mailto", "mailto:jdoe@example.com", true},
+		{"route", "/all", true},
+		{"static", "/static/img/cat.png", true},
+		{"escaped", "/my%20not%C3%A9s/", true},
+		{"missing", "/blog/gone.html", false},
+		{"missing relative", "gone.html", false},
+		{"directory with an index", "/blog/", true},
+		{"directory without an index", "/notes/", false},
+		{"directory without an index or slash", "/static/img", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolves(outputDir, "blog/post.html", tt.ref, []string{"/all"}); got != tt.want {
+				t.Errorf("resolves(%q) = %v, want %v", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindLine(t *testing.T) {
+	lines := []string{
+		"---",
+		"title: links to / and /all in the text",
+		"---",
+		"a [link](/gone.html) and ![an image](cat.png \"a cat\")",
+		"[ref]: /ref.html",
+		"<https://example.com/a>",
+		`<a href="/html.html">`,
+		`{{< figure src="/static/fig.png" >}}`,
+	}
+	tests := []struct {
+		ref  string
+		want int
+	}{
+		{"/gone.html", 4},
+		{"cat.png", 4},
+		{"/ref.html", 5},
+		{"https://example.com/a", 6},
+		{"/html.html", 7},
+		{"/static/fig.png", 8},
+		// in the text but not a link
+		{"/", 0},
+		{"/all", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := findLine(lines, tt.ref); got != tt.want {
+			t.Errorf("findLine(%q) = %d, want %d", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestLinks(t *testing.T) {
+	dir := t.TempDir()
+	outputDir := filepath.Join(dir, "out")
+	source := filepath.Join(dir, "content", "blog", "post.md")
+	writeFiles(t, dir, map[string]string{
+		"content/blog/post.md": "---\ntitle: post\n---\nsee / and [gone](/gone.html)\n\n![missing](missing.png)\n",
+		"out/index.html":       "<a href=\"/\">home</a>",
+		"out/blog/post.html": "<html>\n<a href=\"/\">home</a> <a href=\"/all\">all</a> <a href=\"/layout-gone\">x</a>\n" +
+			"<p>see / and <a href=\"/gone.html\">gone</a></p>\n<img\n  src=\"missing.png\">\n</html>",
+	})
+	got, err := Links(outputDir, map[string]string{"blog/post.html": source}, []string{"/all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Problem{
+		{File: source, Line: 4, Message: `broken link "/gone.html" in blog/post.html`},
+		{File: source, Line: 6, Message: `broken link "missing.png" in blog/post.html`},
+		// not in the content, so it is reported in the html file
+		{File: filepath.Join(outputDir, "blog", "post.html"), Line: 2, Message: `broken link "/layout-gone"`},
+	}
+	Sort(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Links() =\n%v\nwant\n%v", got, want)
+	}
+}
@@ -3,13 +3,17 @@ package cli
 import (
 	"flag"
 	"fmt"
+	"github.com/jcocozza/jbf/internal/author"
+	"github.com/jcocozza/jbf/internal/check"
+	"github.com/jcocozza/jbf/internal/config"
 	"github.com/jcocozza/jbf/internal/dal/sqlite"
-	"github.com/jcocozza/jbf/internal/pandoc"
 	"github.com/jcocozza/jbf/internal/serve"
 	"github.com/jcocozza/jbf/internal/service"
-	"html/template"
+	"github.com/jcocozza/jbf/internal/shortcode"
+	"github.com/jcocozza/jbf/internal/theme"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 const (
@@ -19,24 +23,37 @@ const (
 	defaultLayoutPath string = ""
 )
 
-func initService() (*service.Service, error) {
+// initService connects to the db. dates are read back from it in loc
+func initService(loc *time.Location) (*service.Service, error) {
 	db, err := sqlite.Connect()
 	if err != nil {
 		return nil, err
 	}
-	dal := sqlite.NewSQLiteRepository(db)
+	dal := sqlite.NewSQLiteRepository(db, loc)
 	return service.NewService(dal), nil
 }
 
-func initServiceWithClean() (*service.Service, error) {
+func initServiceWithClean(loc *time.Location) (*service.Service, error) {
 	db, err := sqlite.ConnectAndClean()
 	if err != nil {
 		return nil, err
 	}
-	dal := sqlite.NewSQLiteRepository(db)
+	dal := sqlite.NewSQLiteRepository(db, loc)
 	return service.NewService(dal), nil
 }
 
+// loadTheme loads the theme from the config with the static dir and base layout from the command line on top
+func loadTheme(s *service.Service, cfg config.Config, staticDir string, baseLayout string) (*theme.Theme, error) {
+	return theme.Load(theme.Options{
+		Name:       cfg.Theme,
+		ThemesDir:  cfg.ThemesDir,
+		LayoutsDir: cfg.LayoutsDir,
+		StaticDir:  staticDir,
+		BaseLayout: baseLayout,
+		Funcs:      s.Funcs(cfg),
+	})
+}
+
 func help() {
 	fmt.Fprintln(os.Stdout, "Usage")
 	fmt.Fprintf(os.Stdout, "  %s <command> [options]\n", os.Args[0])
@@ -45,6 +62,7 @@ func help() {
 	fmt.Fprintln(os.Stdout, "  compile  compile input to html")
 	fmt.Fprintln(os.Stdout, "  serve    serve content")
 	fmt.Fprintln(os.Stdout, "  new      create a new file in the content directory")
+	fmt.Fprintln(os.Stdout, "  check    validate the front matter and wiki links (and with --links the compiled links) of the content")
 	fmt.Fprintf(os.Stdout, "use %s <command> --help for more details", os.Args[0])
 }
 
@@ -83,62 +101,105 @@ func compileCmd() {
 	var outputDir string
 	var templateLayoutPath string
 	var staticDir string
+	var configPath string
+	var checkLinks bool
 	compileCmd := flag.NewFlagSet("compile", flag.ExitOnError)
+	compileCmd.BoolVar(&checkLinks, "check-links", false, "warn about broken links after compiling")
+	compileCmd.StringVar(&configPath, "config", config.DefaultPath, "the path of the config file")
 	compileCmd.StringVar(&inputDir, "content-dir", defaultContentDir, "the root directory of your content")
 	compileCmd.StringVar(&outputDir, "output-dir", defaultOutputDir, "the root directory of where you want output to be written to")
-	compileCmd.StringVar(&templateLayoutPath, "template-path", defaultLayoutPath, "point to a template file which will wrap each created file during compilation (empty uses default)")
-	compileCmd.StringVar(&staticDir, "static-dir", defaultStaticDir, "the root directory of where static files are located (empty uses default styles)")
+	compileCmd.StringVar(&templateLayoutPath, "template-path", defaultLayoutPath, "point to a template file which will wrap each created file during compilation, in place of the theme's base.html (empty uses the theme)")
+	compileCmd.StringVar(&staticDir, "static-dir", defaultStaticDir, "the root directory of where static files are located, on top of the theme's static files (empty uses the theme's)")
 	h := checkHelp(compileCmd)
 	if h {
 		return
 	}
 	compileCmd.Parse(os.Args[2:])
-	var layout *template.Template = pandoc.DefaultLayout
-	if templateLayoutPath != "" {
-		var err error
-		layout, err = template.ParseFiles(templateLayoutPath)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err.Error())
-			return
-		}
+	siteCfg, err := config.Load(configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return
+	}
+	authors, err := author.Load(siteCfg.AuthorsFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return
 	}
 	fmt.Fprintf(os.Stderr, "content dir: %s\n", inputDir)
 	fmt.Fprintf(os.Stderr, "output dir: %s\n", outputDir)
-	s, err := initServiceWithClean()
+	s, err := initServiceWithClean(siteCfg.Location())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return
+	}
+	t, err := loadTheme(s, siteCfg, staticDir, templateLayoutPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return
+	}
+	shortcodes, err := shortcode.Load(siteCfg.ShortcodesDir, s.Funcs(siteCfg))
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 		return
 	}
 	cfg := service.Config{
-		Layout: layout,
-		Name:   "foo bar",
+		Config:     siteCfg,
+		Theme:      t,
+		Authors:    authors,
+		Shortcodes: shortcodes,
 	}
-	err = s.Compilation(inputDir, outputDir, staticDir, cfg)
+	err = s.Compilation(inputDir, outputDir, cfg)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 		return
 	}
 	fmt.Fprintf(os.Stderr, "content compliled to %s\n", outputDir)
+	if checkLinks {
+		problems, err := s.CheckLinks(inputDir, outputDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return
+		}
+		for _, p := range problems {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", p.String())
+		}
+	}
 }
 
 func serveCmd() {
 	var serveDir string
 	var contentDir string
+	var templateLayoutPath string
+	var staticDir string
+	var configPath string
 	serveCmd := flag.NewFlagSet("serve", flag.ExitOnError)
+	serveCmd.StringVar(&configPath, "config", config.DefaultPath, "the path of the config file")
 	serveCmd.StringVar(&serveDir, "serve-dir", defaultOutputDir, "the root directory of where you files to be served from")
 	serveCmd.StringVar(&contentDir, "content-dir", defaultContentDir, "the root directory of your content")
+	serveCmd.StringVar(&templateLayoutPath, "template-path", defaultLayoutPath, "the template file the content was compiled with, so that the pages rendered while serving match (empty uses the theme)")
+	serveCmd.StringVar(&staticDir, "static-dir", defaultStaticDir, "the static dir the content was compiled with (empty uses the theme's)")
 	h := checkHelp(serveCmd)
 	if h {
 		return
 	}
 	serveCmd.Parse(os.Args[2:])
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return
+	}
 	fmt.Fprintln(os.Stdout, "running serve")
-	s, err := initService()
+	s, err := initService(cfg.Location())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return
+	}
+	t, err := loadTheme(s, cfg, staticDir, templateLayoutPath)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 		return
 	}
-	serve.Server(s, serveDir, contentDir)
+	serve.Server(s, serveDir, contentDir, cfg, t)
 }
 
 func newContentCmd() {
@@ -158,7 +219,8 @@ func newContentCmd() {
 		return
 	}
 	fmt.Fprintf(os.Stdout, "creating file: %s\n", filepath.Join(contentDir, name))
-	s, err := initService()
+	// no dates are read back, so the timezone doesn't matter
+	s, err := initService(time.UTC)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 		return
@@ -170,6 +232,62 @@ func newContentCmd() {
 	}
 }
 
+func checkCmd() {
+	var contentDir string
+	var outputDir string
+	var configPath string
+	var links bool
+	checkCmd := flag.NewFlagSet("check", flag.ExitOnError)
+	checkCmd.StringVar(&contentDir, "content-dir", defaultContentDir, "the root directory of your content")
+	checkCmd.StringVar(&outputDir, "output-dir", defaultOutputDir, "the root directory of the compiled content (used with --links)")
+	checkCmd.BoolVar(&links, "links", false, "also check the compiled content for broken links and missing assets")
+	checkCmd.StringVar(&configPath, "config", config.DefaultPath, "the path of the config file")
+	h := checkHelp(checkCmd)
+	if h {
+		return
+	}
+	checkCmd.Parse(os.Args[2:])
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	problems, err := check.Content(contentDir, cfg.Schema, cfg.Location())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	wikiProblems, err := service.CheckWikiLinks(contentDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	problems = append(problems, wikiProblems...)
+	check.Sort(problems)
+	if links {
+		s, err := initService(cfg.Location())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		linkProblems, err := s.CheckLinks(contentDir, outputDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		problems = append(problems, linkProblems...)
+		check.Sort(problems)
+	}
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, p.String())
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%d problem(s) found\n", len(problems))
+		os.Exit(1)
+	}
+	fmt.Fprintln(os.Stdout, "no problems found")
+}
+
 func root() {
 	flag.Usage = help
 	var h bool
@@ -188,6 +306,8 @@ func root() {
 		serveCmd()
 	case "new":
 		newContentCmd()
+	case "check":
+		checkCmd()
 	default:
 		help()
 	}
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jcocozza/jbf/internal/assets"
+	"github.com/jcocozza/jbf/internal/author"
+	"github.com/jcocozza/jbf/internal/menu"
+	"gopkg.in/yaml.v3"
+)
+
+const DefaultPath string = "jbf.yaml"
+
+// Config is the site configuration read from jbf.yaml
+type Config struct {
+	// the name of the site
+	Name string `yaml:"name"`
+	// the url the site is served at (e.g. https://example.com), used for absolute links in feeds
+	BaseURL string `yaml:"base_url"`
+	// the timezone of dates in the front matter that do not specify one (e.g. America/New_York)
+	Timezone string `yaml:"timezone"`
+	// the go layout used when displaying dates
+	DateFormat string `yaml:"date_format"`
+	// fill in missing created, last_updated and author from the local git history of each content file
+	GitDates bool `yaml:"git_dates"`
+	// the number of related posts passed to the layout of each post
+	Related int `yaml:"related"`
+	// the number of words in summaries taken from the start of the content
+	SummaryWords int `yaml:"summary_words"`
+	// the reading speed used to estimate reading time
+	WordsPerMinute int `yaml:"words_per_minute"`
+	// the number of posts in the feeds
+	FeedItems int `yaml:"feed_items"`
+	// the number of posts per page of the list pages (e.g. /all, tags and sections). 0 puts every post on one page
+	Paginate int `yaml:"paginate"`
+	// the name of the theme, a directory in ThemesDir or the built in default
+	Theme     string `yaml:"theme"`
+	ThemesDir string `yaml:"themes_dir"`
+	// templates that override the layouts of the theme file by file
+	LayoutsDir string `yaml:"layouts_dir"`
+	// the directory of the user defined shortcodes
+	ShortcodesDir string `yaml:"shortcodes_dir"`
+	// named menus of links, e.g. main for the navbar
+	Menus menu.Menus `yaml:"menus"`
+	// the file defining the author profiles
+	AuthorsFile string `yaml:"authors_file"`
+	// how static files are written
+	Assets Assets `yaml:"assets"`
+	// the colors, font and width of the theme
+	Style Style `yaml:"style"`
+	// responsive variants of the images in content
+	Images Images `yaml:"images"`
+	// the link preview images drawn for posts without an image
+	Cards Cards `yaml:"og_cards"`
+	// the list pages generated for content subdirectories
+	Sections Sections `yaml:"sections"`
+	// extra rules that jbf check validates front matter against
+	Schema Schema `yaml:"schema"`
+	// the parsed Timezone, set by Load
+	loc *time.Location
+}
+
+// Assets controls the minification, bundling and fingerprinting of static files
+type Assets struct {
+	// minify css, js and the compiled html
+	Minify bool `yaml:"minify"`
+	// write static files under a name with a hash of their content, for the asset template function
+	Fingerprint bool `yaml:"fingerprint"`
+	// static files joined into one
+	Bundles []assets.Bundle `yaml:"bundles"`
+}
+
+// Style overrides the css variables of the theme. Empty values keep the theme's own.
+type Style struct {
+	// a css color used for links and highlights, e.g. #2563eb
+	AccentColor string `yaml:"accent_color"`
+	// a css font stack, e.g. Georgia, serif
+	FontFamily string `yaml:"font_family"`
+	// the widest the content gets, e.g. 48rem
+	MaxWidth string `yaml:"max_width"`
+}
+
+// Images controls the resized variants made of the static images used in content
+type Images struct {
+	Enabled bool `yaml:"enabled"`
+	// the widths of the variants in pixels
+	Widths []int `yaml:"widths"`
+	// the quality of resized jpegs, 1 to 100
+	Quality int `yaml:"quality"`
+	// also make (lossless) webp versions of png images
+	WebP bool `yaml:"webp"`
+	// the sizes attribute of img tags with variants
+	Sizes string `yaml:"sizes"`
+	// where variants are kept between builds
+	CacheDir string `yaml:"cache_dir"`
+}
+
+// Cards controls the link preview images drawn for posts that do not set image in their front matter
+type Cards struct {
+	Enabled bool `yaml:"enabled"`
+	// where cards are kept between builds
+	CacheDir string `yaml:"cache_dir"`
+}
+
+type Sections struct {
+	// date (newest first) or title
+	Sort string `yaml:"sort"`
+}
+
+// Schema is the user defined front matter rules
+type Schema struct {
+	// front matter keys that every content file must set
+	Required []string `yaml:"required"`
+	// if not empty, the only tags that may be used
+	AllowedTags []string  `yaml:"allowed_tags"`
+	Created     DateRange `yaml:"created"`
+	LastUpdated DateRange `yaml:"last_updated"`
+}
+
+// DateRange bounds a front matter date. Empty bounds are not checked.
+type DateRange struct {
+	After  string `yaml:"after"`
+	Before string `yaml:"before"`
+}
+
+func Default() Config {
+	return Config{
+		Name:           "foo bar",
+		Timezone:       "UTC",
+		DateFormat:     "2006-01-02",
+		Related:        5,
+		Paginate:       10,
+		SummaryWords:   70,
+		FeedItems:      20,
+		WordsPerMinute: 200,
+		AuthorsFile:    author.DefaultPath,
+		Theme:          "default",
+		ThemesDir:      "themes",
+		LayoutsDir:     "layouts",
+		ShortcodesDir:  "shortcodes",
+		Sections: Sections{
+			Sort: "date",
+		},
+		Images: Images{
+			Widths:   []int{480, 960, 1600},
+			Quality:  80,
+			WebP:     true,
+			Sizes:    "(max-width: 960px) 100vw, 960px",
+			CacheDir: ".jbf_cache/images",
+		},
+		Cards: Cards{
+			Enabled:  true,
+			CacheDir: ".jbf_cache/og",
+		},
+		Menus: menu.Menus{
+			menu.Main: {
+				{Name: "Home", URL: "/"},
+				{Name: "All", URL: "/all"},
+			},
+		},
+	}
+}
+
+// Load reads the config file at path on top of the defaults.
+// A missing file is not an error, the defaults are used instead.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return Config{}, err
+	}
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		return Config{}, fmt.Errorf("unable to parse config %s: %w", path, err)
+	}
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid timezone in config %s: %w", path, err)
+	}
+	cfg.loc = loc
+	if err := cfg.Style.validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid style in config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// cssVar is a style value and the css variable it sets
+type cssVar struct {
+	key   string
+	name  string
+	value string
+}
+
+func (s Style) vars() []cssVar {
+	return []cssVar{
+		{"accent_color", "--accent", s.AccentColor},
+		{"font_family", "--font-family", s.FontFamily},
+		{"max_width", "--max-width", s.MaxWidth},
+	}
+}
+
+// validate rejects values that would end the css declaration they are put in
+func (s Style) validate() error {
+	for _, v := range s.vars() {
+		if strings.ContainsAny(v.value, ";{}<>\\") {
+			return fmt.Errorf("%s %q can't contain ; { } < > or \\", v.key, v.value)
+		}
+	}
+	return nil
+}
+
+// Vars are the css declarations of the values that are set, e.g. --accent: #2563eb;
+func (s Style) Vars() string {
+	decls := []string{}
+	for _, v := range s.vars() {
+		if v.value != "" {
+			decls = append(decls, fmt.Sprintf("%s: %s;", v.name, v.value))
+		}
+	}
+	return strings.Join(decls, " ")
+}
+
+// Location is the timezone of front matter dates that do not specify one.
+// Load rejects unknown timezones, so only a config that wasn't loaded can fall back to UTC.
+func (c Config) Location() *time.Location {
+	if c.loc != nil {
+		return c.loc
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
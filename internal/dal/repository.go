@@ -2,17 +2,41 @@ package dal
 
 import "github.com/jcocozza/jbf/internal/metadata"
 
+// Query filters and sorts metadata
+type Query struct {
+	// front matter fields that must be equal to the given value, e.g. series or a custom field.
+	// author and tags keep the content with the value among its authors or tags
+	Params map[string]any
+	// if set, only content with the tag
+	Tag string
+	// if set, only content by the author
+	Author string
+	// a front matter field, e.g. title, series_order or a custom field. defaults to created
+	SortBy string
+	// sort in ascending order instead of descending
+	Asc bool
+	// the most results to return. 0 is no limit
+	Limit int
+}
+
 type Repository interface {
 	CreateTag(metadataID int, name string) error
-	ReadTagExists(tagName string) bool
 	ReadTags(metadataID int) ([]string, error)
 	DeleteTag(tagName string) error
 
+	// CreateAuthor adds an author to the content. position orders the authors of the content
+	CreateAuthor(metadataID int, name string, position int) error
+
 	CreateMetadata(m metadata.Metadata) (int, error)
 	ReadMetadataExists(filepath string) bool
 	ReadMetadata(filepath string) (metadata.Metadata, error)
 	ReadMetadataFiles() ([]string, error)
 	ReadAllMetadata() ([]metadata.Metadata, error)
+	QueryMetadata(q Query) ([]metadata.Metadata, error)
 	UpdateMetadata(m metadata.Metadata) error
 	DeleteMetadata(filepath string) error
+
+	CreateLink(sourceID int, targetID int) error
+	// ReadBacklinks returns the content that links to targetID
+	ReadBacklinks(targetID int) ([]metadata.Metadata, error)
 }
@@ -48,11 +48,11 @@ func ConnectAndClean() (*sql.DB, error) {
 	if err != nil {
 		return nil, err
 	}
-	err = Schema(db)
+	err = Reset(db)
 	if err != nil {
 		return nil, err
 	}
-	err = Truncate(db)
+	err = Schema(db)
 	if err != nil {
 		return nil, err
 	}
@@ -67,10 +67,11 @@ func Schema(db *sql.DB) error {
 	return err
 }
 
-//go:embed truncate.sql
-var truncate string
+//go:embed reset.sql
+var reset string
 
-func Truncate(db *sql.DB) error {
-	_, err := db.Exec(truncate)
+// Reset drops all tables so that Schema recreates them with the current columns
+func Reset(db *sql.DB) error {
+	_, err := db.Exec(reset)
 	return err
 }
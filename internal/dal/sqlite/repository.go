@@ -1,33 +1,128 @@
 package sqlite
 
 import (
+	"github.com/jcocozza/jbf/internal/dal"
 	"github.com/jcocozza/jbf/internal/metadata"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 )
 
+const metadataColumns = "id, filepath, url, title, author, created, last_updated, series, series_order, summary, description, image, word_count, reading_time, menus, menu_weight, layout, params, " +
+	"(select json_group_array(tag_name) from tag where tag.metadata_id = metadata.id) as tags, " +
+	"(select json_group_array(author_name) from (select author_name from author where author.metadata_id = metadata.id order by position)) as authors"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMetadata(row scanner, loc *time.Location) (metadata.Metadata, error) {
+	var m metadata.Metadata
+	var params string
+	var menus string
+	var tags string
+	var authors string
+	err := row.Scan(&m.ID, &m.Filepath, &m.URL, &m.Title, &m.Author, &m.Created, &m.LastUpdated, &m.Series, &m.SeriesOrder, &m.Summary, &m.Description, &m.Image, &m.WordCount, &m.ReadingTime, &menus, &m.MenuWeight, &m.Layout, &params, &tags, &authors)
+	if err != nil {
+		return metadata.Metadata{}, err
+	}
+	err = json.Unmarshal([]byte(tags), &m.Tags)
+	if err != nil {
+		return metadata.Metadata{}, err
+	}
+	err = json.Unmarshal([]byte(authors), &m.Authors)
+	if err != nil {
+		return metadata.Metadata{}, err
+	}
+	err = json.Unmarshal([]byte(menus), &m.Menu)
+	if err != nil {
+		return metadata.Metadata{}, err
+	}
+	m.Created, m.LastUpdated = m.Created.In(loc), m.LastUpdated.In(loc)
+	if params != "{}" {
+		err = json.Unmarshal([]byte(params), &m.Params)
+		if err != nil {
+			return metadata.Metadata{}, err
+		}
+	}
+	return m, nil
+}
+
+func encodeParams(m metadata.Metadata) (string, error) {
+	if len(m.Params) == 0 {
+		return "{}", nil
+	}
+	b, err := json.Marshal(m.Params)
+	return string(b), err
+}
+
+// columns are the front matter keys that have a column of their own. the rest are in the params column
+var columns = map[string]string{
+	"url":          "url",
+	"title":        "title",
+	"author":       "author",
+	"created":      "created",
+	"last_updated": "last_updated",
+	"series":       "series",
+	"series_order": "series_order",
+	"summary":      "summary",
+	"description":  "description",
+	"image":        "image",
+	"word_count":   "word_count",
+	"reading_time": "reading_time",
+	"menu_weight":  "menu_weight",
+	"layout":       "layout",
+}
+
+// dates are stored as timestamps, so they can be sorted on but not compared with a value from a query
+var dateColumns = map[string]bool{"created": true, "last_updated": true}
+
+// condition is the sql condition that the front matter key equals value
+func condition(key string, value any) (string, []any, error) {
+	switch {
+	case key == "author" || key == "authors":
+		// any of the authors, not only the main one
+		return "exists (select 1 from author where author.metadata_id = metadata.id and author_name = ?)", []any{fmt.Sprint(value)}, nil
+	case key == "tags":
+		return "exists (select 1 from tag where tag.metadata_id = metadata.id and tag_name = ?)", []any{fmt.Sprint(value)}, nil
+	case dateColumns[key]:
+		return "", nil, fmt.Errorf("unable to filter on %s, it can only be sorted on", key)
+	case columns[key] != "":
+		// the column's affinity converts the value, e.g. '2' to 2 for series_order
+		return columns[key] + " = ?", []any{fmt.Sprint(value)}, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", nil, err
+	}
+	return "json_extract(params, ?) = json_extract(?, '$')", []any{paramPath(key), string(encoded)}, nil
+}
+
+// paramPath is the json path of a custom front matter field in the params column
+func paramPath(key string) string {
+	return `$."` + strings.ReplaceAll(key, `"`, `\"`) + `"`
+}
+
 type SQLiteRepository struct {
 	db *sql.DB
+	// dates are stored in utc and read back in loc
+	loc *time.Location
 }
 
-func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
-	return &SQLiteRepository{db: db}
+func NewSQLiteRepository(db *sql.DB, loc *time.Location) *SQLiteRepository {
+	return &SQLiteRepository{db: db, loc: loc}
 }
 
 func (r *SQLiteRepository) CreateTag(metadataID int, name string) error {
-	_, err := r.db.Exec("insert into tag (metadata_id, tag_name) values (?,?)", metadataID, name)
+	_, err := r.db.Exec("insert or ignore into tag (metadata_id, tag_name) values (?,?)", metadataID, name)
 	return err
 }
 
-func (r *SQLiteRepository) ReadTagExists(tagName string) bool {
-	row := r.db.QueryRow("select tag_name from tag where tag_name = ?;", tagName)
-	var fpath string
-	err := row.Scan(&fpath)
-	return err != sql.ErrNoRows
-}
-
 func (r *SQLiteRepository) ReadTags(metadataID int) ([]string, error) {
-	rows, err := r.db.Query("select tag_name from tags where metadata_id = ?", metadataID)
+	rows, err := r.db.Query("select tag_name from tag where metadata_id = ?", metadataID)
 	if err == sql.ErrNoRows {
 		return []string{}, nil
 	} else if err != nil {
@@ -51,9 +146,31 @@ func (r *SQLiteRepository) DeleteTag(tagName string) error {
 	return err
 }
 
+func (r *SQLiteRepository) CreateAuthor(metadataID int, name string, position int) error {
+	_, err := r.db.Exec("insert or ignore into author (metadata_id, author_name, position) values (?,?,?)", metadataID, name, position)
+	return err
+}
+
+// encodeMenus is the json array of the menus of the content
+func encodeMenus(m metadata.Metadata) (string, error) {
+	if len(m.Menu) == 0 {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]string(m.Menu))
+	return string(b), err
+}
+
 func (r *SQLiteRepository) CreateMetadata(m metadata.Metadata) (int, error) {
-	q := "insert into metadata (filepath, title, author, created, last_updated) values (?,?,?,?,?)"
-	result, err := r.db.Exec(q, m.Filepath, m.Title, m.Author, time.Time(m.Created), time.Time(m.LastUpdated))
+	params, err := encodeParams(m)
+	if err != nil {
+		return -1, err
+	}
+	menus, err := encodeMenus(m)
+	if err != nil {
+		return -1, err
+	}
+	q := "insert into metadata (filepath, url, title, author, created, last_updated, series, series_order, summary, description, image, word_count, reading_time, menus, menu_weight, layout, params) values (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)"
+	result, err := r.db.Exec(q, m.Filepath, m.URL, m.Title, m.Author, time.Time(m.Created).UTC(), time.Time(m.LastUpdated).UTC(), m.Series, m.SeriesOrder, m.Summary, m.Description, m.Image, m.WordCount, m.ReadingTime, menus, m.MenuWeight, m.Layout, params)
 	if err != nil {
 		return -1, err
 	}
@@ -69,9 +186,8 @@ func (r *SQLiteRepository) ReadMetadataExists(filepath string) bool {
 }
 
 func (r *SQLiteRepository) ReadMetadata(filepath string) (metadata.Metadata, error) {
-	row := r.db.QueryRow("select id, filepath, title, author, created, last_updated from metadata where filepath = ?", filepath)
-	var m metadata.Metadata
-	err := row.Scan(&m.ID, &m.Filepath, &m.Title, &m.Author, &m.Created, &m.LastUpdated)
+	row := r.db.QueryRow("select "+metadataColumns+" from metadata where filepath = ?", filepath)
+	m, err := scanMetadata(row, r.loc)
 	if err != nil {
 		return metadata.Metadata{}, err
 	}
@@ -97,28 +213,86 @@ func (r *SQLiteRepository) ReadMetadataFiles() ([]string, error) {
 }
 
 func (r *SQLiteRepository) ReadAllMetadata() ([]metadata.Metadata, error) {
-	rows, err := r.db.Query("select id, filepath, title, author, created, last_updated from metadata ORDER BY created desc")
-	if err == sql.ErrNoRows {
-		return []metadata.Metadata{}, nil
+	return r.QueryMetadata(dal.Query{})
+}
+
+func (r *SQLiteRepository) QueryMetadata(query dal.Query) ([]metadata.Metadata, error) {
+	q := "select " + metadataColumns + " from metadata"
+	args := []any{}
+	keys := make([]string, 0, len(query.Params))
+	for key := range query.Params {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
+	conditions := []string{}
+	for _, key := range keys {
+		cond, condArgs, err := condition(key, query.Params[key])
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, cond)
+		args = append(args, condArgs...)
+	}
+	if query.Tag != "" {
+		conditions = append(conditions, "exists (select 1 from tag where tag.metadata_id = metadata.id and tag_name = ?)")
+		args = append(args, query.Tag)
+	}
+	if query.Author != "" {
+		conditions = append(conditions, "exists (select 1 from author where author.metadata_id = metadata.id and author_name = ?)")
+		args = append(args, query.Author)
+	}
+	if len(conditions) > 0 {
+		q += " where " + strings.Join(conditions, " and ")
+	}
+	switch {
+	case query.SortBy == "":
+		q += " order by created"
+	case columns[query.SortBy] != "":
+		q += " order by " + columns[query.SortBy]
+	default:
+		q += " order by json_extract(params, ?)"
+		args = append(args, paramPath(query.SortBy))
+	}
+	if query.Asc {
+		q += " asc, filepath"
+	} else {
+		q += " desc, filepath"
+	}
+	if query.Limit > 0 {
+		q += " limit ?"
+		args = append(args, query.Limit)
+	}
+	return r.queryMetadata(q, args...)
+}
+
+func (r *SQLiteRepository) queryMetadata(q string, args ...any) ([]metadata.Metadata, error) {
+	rows, err := r.db.Query(q, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	mLst := []metadata.Metadata{}
 	for rows.Next() {
-		m := metadata.Metadata{}
-		err := rows.Scan(&m.ID, &m.Filepath, &m.Title, &m.Author, &m.Created, &m.LastUpdated)
+		m, err := scanMetadata(rows, r.loc)
 		if err != nil {
 			return nil, err
 		}
 		mLst = append(mLst, m)
 	}
-	return mLst, nil
+	return mLst, rows.Err()
 }
 
 func (r *SQLiteRepository) UpdateMetadata(m metadata.Metadata) error {
-	q := "update metadata set title = ?, author = ?, created = ?, last_updated = ? where filepath = ?"
-	_, err := r.db.Exec(q, m.Title, m.Author, time.Time(m.Created), time.Time(m.LastUpdated), m.Filepath)
+	params, err := encodeParams(m)
+	if err != nil {
+		return err
+	}
+	menus, err := encodeMenus(m)
+	if err != nil {
+		return err
+	}
+	q := "update metadata set url = ?, title = ?, author = ?, created = ?, last_updated = ?, series = ?, series_order = ?, summary = ?, description = ?, image = ?, word_count = ?, reading_time = ?, menus = ?, menu_weight = ?, layout = ?, params = ? where filepath = ?"
+	_, err = r.db.Exec(q, m.URL, m.Title, m.Author, time.Time(m.Created).UTC(), time.Time(m.LastUpdated).UTC(), m.Series, m.SeriesOrder, m.Summary, m.Description, m.Image, m.WordCount, m.ReadingTime, menus, m.MenuWeight, m.Layout, params, m.Filepath)
 	return err
 }
 
@@ -126,3 +300,13 @@ func (r SQLiteRepository) DeleteMetadata(filepath string) error {
 	_, err := r.db.Exec("delete from metadata where filepath = ?;", filepath)
 	return err
 }
+
+func (r *SQLiteRepository) CreateLink(sourceID int, targetID int) error {
+	_, err := r.db.Exec("insert or ignore into link (source_id, target_id) values (?,?)", sourceID, targetID)
+	return err
+}
+
+func (r *SQLiteRepository) ReadBacklinks(targetID int) ([]metadata.Metadata, error) {
+	q := "select " + metadataColumns + " from metadata where id in (select source_id from link where target_id = ?) order by created desc, filepath"
+	return r.queryMetadata(q, targetID)
+}
@@ -0,0 +1,96 @@
+package sqlite
+
+import (
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jcocozza/jbf/internal/dal"
+	"github.com/jcocozza/jbf/internal/metadata"
+)
+
+// testRepository is a repository with the content of a small site
+func testRepository(t *testing.T) *SQLiteRepository {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "jbf.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := Schema(db); err != nil {
+		t.Fatal(err)
+	}
+	r := NewSQLiteRepository(db, time.UTC)
+	day := func(d int) metadata.Date {
+		return metadata.Date(time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC))
+	}
+	content := []metadata.Metadata{
+		{Filepath: "a.md", Title: "a", Author: "jdoe", Authors: []string{"jdoe", "asmith"}, Created: day(1), Series: "intro", SeriesOrder: 2, Layout: "slides", Params: map[string]any{"weight": 3, "featured": true}},
+		{Filepath: "b.md", Title: "b", Author: "asmith", Authors: []string{"asmith"}, Created: day(2), Series: "intro", SeriesOrder: 1, Params: map[string]any{"weight": 1}},
+		{Filepath: "c.md", Title: "c", Created: day(3), Series: "other", SeriesOrder: 3, Tags: []string{"go"}, Params: map[string]any{"weight": 2}},
+	}
+	for _, m := range content {
+		id, err := r.CreateMetadata(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, a := range m.Authors {
+			if err := r.CreateAuthor(id, a, i); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, tag := range m.Tags {
+			if err := r.CreateTag(id, tag); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return r
+}
+
+func TestQueryMetadata(t *testing.T) {
+	tests := []struct {
+		name  string
+		query dal.Query
+		want  []string
+	}{
+		{"newest first", dal.Query{}, []string{"c.md", "b.md", "a.md"}},
+		{"series", dal.Query{Params: map[string]any{"series": "intro"}}, []string{"b.md", "a.md"}},
+		{"series in order", dal.Query{Params: map[string]any{"series": "intro"}, SortBy: "series_order", Asc: true}, []string{"b.md", "a.md"}},
+		{"series order", dal.Query{Params: map[string]any{"series_order": 2}}, []string{"a.md"}},
+		{"series order from a url", dal.Query{Params: map[string]any{"series_order": "2"}}, []string{"a.md"}},
+		{"layout", dal.Query{Params: map[string]any{"layout": "slides"}}, []string{"a.md"}},
+		{"main author", dal.Query{Params: map[string]any{"author": "jdoe"}}, []string{"a.md"}},
+		{"any author", dal.Query{Params: map[string]any{"author": "asmith"}}, []string{"b.md", "a.md"}},
+		{"tags", dal.Query{Params: map[string]any{"tags": "go"}}, []string{"c.md"}},
+		{"custom field", dal.Query{Params: map[string]any{"featured": true}}, []string{"a.md"}},
+		{"custom field sort", dal.Query{SortBy: "weight", Asc: true}, []string{"b.md", "c.md", "a.md"}},
+		{"field and custom field", dal.Query{Params: map[string]any{"series": "intro", "weight": 1}}, []string{"b.md"}},
+		{"title", dal.Query{SortBy: "title", Asc: true, Limit: 2}, []string{"a.md", "b.md"}},
+		{"no match", dal.Query{Params: map[string]any{"series": "missing"}}, []string{}},
+	}
+	r := testRepository(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.QueryMetadata(tt.query)
+			if err != nil {
+				t.Fatalf("QueryMetadata() error = %v", err)
+			}
+			files := []string{}
+			for _, m := range got {
+				files = append(files, m.Filepath)
+			}
+			if !slices.Equal(files, tt.want) {
+				t.Errorf("QueryMetadata() = %v, want %v", files, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryMetadataDateFilter(t *testing.T) {
+	if _, err := testRepository(t).QueryMetadata(dal.Query{Params: map[string]any{"created": "2024-01-01"}}); err == nil {
+		t.Error("QueryMetadata() filtering on created should fail")
+	}
+}
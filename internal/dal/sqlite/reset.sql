@@ -0,0 +1,4 @@
+drop table if exists author;
+drop table if exists link;
+drop table if exists tag;
+drop table if exists metadata;
@@ -1,19 +1,52 @@
 create table if not exists metadata (
     id integer primary key,
     filepath text not null,
+    url text not null default '',
     title text,
     author text,
     created datetime,
-    last_updated datetime
+    last_updated datetime,
+    series text not null default '',
+    series_order integer not null default 0,
+    summary text not null default '',
+    description text not null default '',
+    image text not null default '',
+    word_count integer not null default 0,
+    reading_time integer not null default 0,
+    -- the menus the content is listed in as a json array
+    menus text not null default '[]',
+    menu_weight integer not null default 0,
+    layout text not null default '',
+    -- custom front matter fields as a json object
+    params text not null default '{}'
 );
 
 create table if not exists tag (
-    tag_name text primary key,
-    metadata_id integer,
+    tag_name text not null,
+    metadata_id integer not null,
 
+    primary key (tag_name, metadata_id),
     foreign key (metadata_id) references metadata (id)
 );
 
+create table if not exists author (
+    author_name text not null,
+    metadata_id integer not null,
+    position integer not null,
+
+    primary key (author_name, metadata_id),
+    foreign key (metadata_id) references metadata (id)
+);
+
+-- a wiki link from one piece of content to another
+create table if not exists link (
+    source_id integer,
+    target_id integer,
+
+    primary key (source_id, target_id),
+    foreign key (source_id) references metadata (id),
+    foreign key (target_id) references metadata (id)
+);
 
 create trigger if not exists delete_metadata
 after delete on metadata
@@ -21,4 +54,8 @@ for each row
 begin
 delete from tag
 where metadata_id = old.id;
+delete from author
+where metadata_id = old.id;
+delete from link
+where source_id = old.id or target_id = old.id;
 end;
@@ -1,2 +0,0 @@
-delete from metadata;
-delete from tag;
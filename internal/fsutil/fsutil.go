@@ -0,0 +1,29 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+)
+
+/*
+WriteReadOnly writes content to path as a read only file, creating the directories it is in.
+a file already at path is replaced, even if it is read only, e.g. when two layers of static files have the same name.
+*/
+func WriteReadOnly(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// a read only file can't be opened for writing, but it can be removed from a writable directory
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0444)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(content); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,45 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteReadOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []byte
+	}{
+		{"new file", nil},
+		{"existing read only file", []byte("old")},
+		{"existing empty file", []byte{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "a", "b", "file.html")
+			if tt.existing != nil {
+				if err := WriteReadOnly(path, tt.existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := WriteReadOnly(path, []byte("new")); err != nil {
+				t.Fatalf("WriteReadOnly() error = %v", err)
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != "new" {
+				t.Errorf("content = %q, want %q", content, "new")
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0444 {
+				t.Errorf("mode = %v, want read only", info.Mode().Perm())
+			}
+		})
+	}
+}
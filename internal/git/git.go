@@ -0,0 +1,61 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// History is what the local git history knows about a file
+type History struct {
+	// the date of the first commit of the file
+	Created time.Time
+	// the date of the last commit of the file
+	LastUpdated time.Time
+	// the author of the last commit of the file
+	LastAuthor string
+}
+
+// InRepository reports whether dir is inside a git work tree
+func InRepository(dir string) bool {
+	cmd := exec.Command("git", "rev-parse", "--is-inside-work-tree")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// FileHistory reads the history of the file from the local repository, following renames.
+// ok is false when the file has no commits, e.g. it is new.
+func FileHistory(path string) (History, bool, error) {
+	cmd := exec.Command("git", "log", "--follow", "--format=%aI%x1f%an", "--", filepath.Base(path))
+	cmd.Dir = filepath.Dir(path)
+	out, err := cmd.Output()
+	if err != nil {
+		return History{}, false, fmt.Errorf("unable to read git history of %s: %w", path, err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) == 0 || lines[0] == "" {
+		return History{}, false, nil
+	}
+	// newest commit first
+	last, lastAuthor, err := parseLine(lines[0])
+	if err != nil {
+		return History{}, false, err
+	}
+	first, _, err := parseLine(lines[len(lines)-1])
+	if err != nil {
+		return History{}, false, err
+	}
+	return History{Created: first, LastUpdated: last, LastAuthor: lastAuthor}, true, nil
+}
+
+func parseLine(line string) (time.Time, string, error) {
+	date, author, _ := strings.Cut(line, "\x1f")
+	t, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("unexpected git log output %q: %w", line, err)
+	}
+	return t, author, nil
+}
@@ -0,0 +1,262 @@
+package images
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/jcocozza/jbf/internal/fsutil"
+	"golang.org/x/image/draw"
+)
+
+// the url the variants are written under
+const variantsURL = "/static/_img/"
+
+// Options controls the variants made for each image
+type Options struct {
+	// the widths of the variants. widths larger than the image are skipped
+	Widths []int
+	// the quality of resized jpegs, 1 to 100
+	Quality int
+	// also make webp variants of png images
+	WebP bool
+	// the sizes attribute of the rewritten img tags
+	Sizes string
+	// variants are kept here between builds so unchanged images are not resized again
+	CacheDir string
+}
+
+// Variant is a resized version of an image
+type Variant struct {
+	URL   string
+	Width int
+}
+
+// Image is an image from the static files with its variants
+type Image struct {
+	URL    string
+	Width  int
+	Height int
+	// variants in the same format as the image, smallest first
+	Variants []Variant
+	// webp variants, smallest first. empty unless the image is a png and webp is enabled
+	WebP []Variant
+	// the url of the full size image encoded again at the configured quality (or compression for pngs).
+	// empty when that is not smaller than the original
+	Optimized string
+}
+
+// FullURL is the url of the smallest full size version of the image
+func (img *Image) FullURL() string {
+	if img.Optimized != "" {
+		return img.Optimized
+	}
+	return img.URL
+}
+
+// Processor makes the variants of the images referenced in content and writes them to the output dir
+type Processor struct {
+	opts      Options
+	static    fs.FS
+	outputDir string
+	// the images that are already processed, by their path in static
+	done map[string]*Image
+}
+
+// New makes a processor for the images in static, which is served at /static/
+func New(opts Options, static fs.FS, outputDir string) *Processor {
+	return &Processor{
+		opts:      opts,
+		static:    static,
+		outputDir: outputDir,
+		done:      map[string]*Image{},
+	}
+}
+
+// staticPath is the path in the static files of the image at src on the page at pageURL.
+// images that are not static files are not processed.
+func staticPath(src string, pageURL string) (string, bool) {
+	if src == "" || strings.Contains(src, ":") || strings.HasPrefix(src, "//") {
+		return "", false
+	}
+	if i := strings.IndexAny(src, "?#"); i >= 0 {
+		src = src[:i]
+	}
+	if !strings.HasPrefix(src, "/") {
+		src = path.Join(path.Dir(pageURL), src)
+	}
+	if !strings.HasPrefix(src, "/static/") {
+		return "", false
+	}
+	return strings.TrimPrefix(src, "/static/"), true
+}
+
+/*
+Process makes the variants of the image at src on the page at pageURL.
+it returns false for images it can't process, e.g. external images, svgs or missing files.
+*/
+func (p *Processor) Process(src string, pageURL string) (*Image, bool, error) {
+	name, ok := staticPath(src, pageURL)
+	if !ok {
+		return nil, false, nil
+	}
+	if img, ok := p.done[name]; ok {
+		return img, img != nil, nil
+	}
+	content, err := fs.ReadFile(p.static, name)
+	if err != nil {
+		// missing images are reported by jbf check --links
+		p.done[name] = nil
+		return nil, false, nil
+	}
+	decoded, format, err := image.Decode(bytes.NewReader(content))
+	if err != nil || (format != "jpeg" && format != "png") {
+		p.done[name] = nil
+		return nil, false, nil
+	}
+	bounds := decoded.Bounds()
+	img := &Image{URL: "/static/" + name, Width: bounds.Dx(), Height: bounds.Dy()}
+	// the quality is part of the hash so that changing it makes new variants
+	h := sha256.New()
+	h.Write(content)
+	fmt.Fprintf(h, "q%d", p.opts.Quality)
+	hash := hex.EncodeToString(h.Sum(nil))[:12]
+	base := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	ext := map[string]string{"jpeg": ".jpg", "png": ".png"}[format]
+	optimized := fmt.Sprintf("%s-%s%s", base, hash, ext)
+	smaller, err := p.optimize(optimized, content, decoded, format)
+	if err != nil {
+		return nil, false, err
+	}
+	if smaller {
+		img.Optimized = variantsURL + optimized
+	}
+	widths := append([]int{}, p.opts.Widths...)
+	sort.Ints(widths)
+	for _, w := range widths {
+		if w <= 0 || w >= img.Width {
+			continue
+		}
+		file := fmt.Sprintf("%s-%s-%dw%s", base, hash, w, ext)
+		if err := p.variant(file, decoded, w, format); err != nil {
+			return nil, false, err
+		}
+		img.Variants = append(img.Variants, Variant{URL: variantsURL + file, Width: w})
+		// the webp encoder is lossless, which makes photos larger than the jpeg they came from, so only pngs get webp versions
+		if p.opts.WebP && format == "png" {
+			file := fmt.Sprintf("%s-%s-%dw.webp", base, hash, w)
+			if err := p.variant(file, decoded, w, "webp"); err != nil {
+				return nil, false, err
+			}
+			img.WebP = append(img.WebP, Variant{URL: variantsURL + file, Width: w})
+		}
+	}
+	if p.opts.WebP && format == "png" {
+		// the full size webp
+		file := fmt.Sprintf("%s-%s.webp", base, hash)
+		if err := p.variant(file, decoded, img.Width, "webp"); err != nil {
+			return nil, false, err
+		}
+		img.WebP = append(img.WebP, Variant{URL: variantsURL + file, Width: img.Width})
+	}
+	p.done[name] = img
+	return img, true, nil
+}
+
+/*
+variant writes the image resized to width in format to the output dir, reusing the cached one if it was made before.
+file names include a hash of the image, so a file that is already in the output dir (e.g. from the same image at another path) is left as it is.
+*/
+func (p *Processor) variant(file string, src image.Image, width int, format string) error {
+	dest := filepath.Join(p.outputDir, filepath.FromSlash(variantsURL), file)
+	if _, err := os.Stat(dest); err == nil {
+		return nil
+	}
+	content, err := p.cached(file, func() ([]byte, error) {
+		return encode(resize(src, width), format, p.opts.Quality)
+	})
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteReadOnly(dest, content)
+}
+
+// optimize writes the image encoded again at full size if that is smaller than original. it reports whether it was smaller.
+func (p *Processor) optimize(file string, original []byte, src image.Image, format string) (bool, error) {
+	dest := filepath.Join(p.outputDir, filepath.FromSlash(variantsURL), file)
+	if _, err := os.Stat(dest); err == nil {
+		return true, nil
+	}
+	content, err := p.cached(file, func() ([]byte, error) {
+		encoded, err := encode(src, format, p.opts.Quality)
+		if err != nil || len(encoded) < len(original) {
+			return encoded, err
+		}
+		// the original is cached when it is already the smallest, so it is not encoded again on the next build
+		return original, nil
+	})
+	if err != nil || len(content) >= len(original) {
+		return false, err
+	}
+	return true, fsutil.WriteReadOnly(dest, content)
+}
+
+// cached returns the file from the cache dir, making it with encodeFile and caching it if it isn't there
+func (p *Processor) cached(file string, encodeFile func() ([]byte, error)) ([]byte, error) {
+	if p.opts.CacheDir == "" {
+		return encodeFile()
+	}
+	cached := filepath.Join(p.opts.CacheDir, file)
+	if content, err := os.ReadFile(cached); err == nil {
+		return content, nil
+	}
+	content, err := encodeFile()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(p.opts.CacheDir, 0755); err != nil {
+		return nil, err
+	}
+	return content, os.WriteFile(cached, content, 0644)
+}
+
+func resize(src image.Image, width int) image.Image {
+	b := src.Bounds()
+	if width == b.Dx() {
+		return src
+	}
+	height := (b.Dy()*width + b.Dx()/2) / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+	return dst
+}
+
+func encode(img image.Image, format string, quality int) ([]byte, error) {
+	var b bytes.Buffer
+	var err error
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&b, img, &jpeg.Options{Quality: quality})
+	case "png":
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&b, img)
+	case "webp":
+		err = nativewebp.Encode(&b, img, nil)
+	default:
+		err = fmt.Errorf("unsupported image format %s", format)
+	}
+	return b.Bytes(), err
+}
@@ -0,0 +1,99 @@
+package images
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+func srcset(variants []Variant) string {
+	parts := make([]string, 0, len(variants))
+	for _, v := range variants {
+		parts = append(parts, fmt.Sprintf("%s %dw", v.URL, v.Width))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// imgTag renders an img tag with the attributes of the original one, the srcset of the image and its size
+func (p *Processor) imgTag(attrs []html.Attribute, img *Image) string {
+	var b strings.Builder
+	b.WriteString("<img")
+	set := map[string]bool{}
+	for _, a := range attrs {
+		set[a.Key] = true
+		if a.Key == "src" {
+			a.Val = img.FullURL()
+		}
+		b.WriteString(fmt.Sprintf(" %s=\"%s\"", a.Key, template.HTMLEscapeString(a.Val)))
+	}
+	if len(img.Variants) > 0 && !set["srcset"] {
+		variants := append(append([]Variant{}, img.Variants...), Variant{URL: img.FullURL(), Width: img.Width})
+		b.WriteString(fmt.Sprintf(" srcset=\"%s\"", template.HTMLEscapeString(srcset(variants))))
+		if !set["sizes"] && p.opts.Sizes != "" {
+			b.WriteString(fmt.Sprintf(" sizes=\"%s\"", template.HTMLEscapeString(p.opts.Sizes)))
+		}
+	}
+	if !set["width"] && !set["height"] {
+		b.WriteString(fmt.Sprintf(" width=\"%d\" height=\"%d\"", img.Width, img.Height))
+	}
+	if !set["loading"] {
+		b.WriteString(" loading=\"lazy\"")
+	}
+	b.WriteString(" />")
+	return b.String()
+}
+
+/*
+Rewrite processes the images of the rendered html of the page at pageURL and adds their variants to the img tags.
+pngs with webp variants are wrapped in a picture with a webp source.
+everything else in the html is left as it is.
+*/
+func (p *Processor) Rewrite(content string, pageURL string) (string, error) {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(content))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() == io.EOF {
+				return b.String(), nil
+			}
+			return "", z.Err()
+		}
+		raw := string(z.Raw())
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			b.WriteString(raw)
+			continue
+		}
+		token := z.Token()
+		if token.Data != "img" {
+			b.WriteString(raw)
+			continue
+		}
+		src := ""
+		for _, a := range token.Attr {
+			if a.Key == "src" {
+				src = a.Val
+			}
+		}
+		img, ok, err := p.Process(src, pageURL)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			b.WriteString(raw)
+			continue
+		}
+		tag := p.imgTag(token.Attr, img)
+		if len(img.WebP) > 0 {
+			source := fmt.Sprintf("<source type=\"image/webp\" srcset=\"%s\"", template.HTMLEscapeString(srcset(img.WebP)))
+			if p.opts.Sizes != "" {
+				source += fmt.Sprintf(" sizes=\"%s\"", template.HTMLEscapeString(p.opts.Sizes))
+			}
+			tag = "<picture>" + source + " />" + tag + "</picture>"
+		}
+		b.WriteString(tag)
+	}
+}
@@ -0,0 +1,95 @@
+package menu
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jcocozza/jbf/internal/metadata"
+)
+
+// Main is the menu the default theme shows in its header
+const Main string = "main"
+
+// Entry is a link in a menu
+type Entry struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// entries are ordered by weight, lightest first. entries with the same weight keep their order
+	Weight   int     `yaml:"weight"`
+	Children []Entry `yaml:"children"`
+	// the entry links to the page being rendered
+	Active bool `yaml:"-"`
+	// one of the children (or their children) links to the page being rendered
+	HasActive bool `yaml:"-"`
+}
+
+/*
+Menus are named lists of entries. e.g.
+
+	main:
+	  - name: Home
+	    url: /
+	  - name: Docs
+	    url: /docs/
+	    children:
+	      - name: Install
+	        url: /docs/install.html
+*/
+type Menus map[string][]Entry
+
+// Build adds the content that sets menu in its front matter to the menus from the config
+func Build(menus Menus, all []metadata.Metadata) Menus {
+	built := Menus{}
+	for name, entries := range menus {
+		built[name] = append([]Entry{}, entries...)
+	}
+	for _, m := range all {
+		for _, name := range m.Menu {
+			built[name] = append(built[name], Entry{Name: m.Title, URL: m.URL, Weight: m.MenuWeight})
+		}
+	}
+	for name := range built {
+		sortEntries(built[name])
+	}
+	return built
+}
+
+func sortEntries(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Weight < entries[j].Weight })
+	for i := range entries {
+		sortEntries(entries[i].Children)
+	}
+}
+
+// normalize makes the different ways of writing the url of a page equal, e.g. /all, /all/ and /all/index.html
+func normalize(u string) string {
+	u = strings.TrimSuffix(u, "index.html")
+	if u != "/" {
+		u = strings.TrimSuffix(u, "/")
+	}
+	return u
+}
+
+// Active is a copy of the menus with the entries that link to the page at url marked
+func (menus Menus) Active(url string) Menus {
+	active := Menus{}
+	for name, entries := range menus {
+		active[name] = activeEntries(entries, normalize(url))
+	}
+	return active
+}
+
+func activeEntries(entries []Entry, url string) []Entry {
+	marked := make([]Entry, len(entries))
+	for i, e := range entries {
+		e.Active = normalize(e.URL) == url
+		e.Children = activeEntries(e.Children, url)
+		for _, c := range e.Children {
+			if c.Active || c.HasActive {
+				e.HasActive = true
+			}
+		}
+		marked[i] = e
+	}
+	return marked
+}
@@ -0,0 +1,268 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// the supported front matter formats
+const (
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+	FormatJSON = "json"
+)
+
+var bom = []byte("\xef\xbb\xbf")
+
+/*
+match everything inbetween the '---' pairs
+e.g.
+---
+this is matched content
+---
+*/
+var yamlRegex = regexp.MustCompile(`(?s)^---[ \t]*\n(?:(.*?)\n)?---[ \t]*(?:\n|$)`)
+
+/*
+match everything inbetween the '+++' pairs
+e.g.
++++
+this is matched content
++++
+*/
+var tomlRegex = regexp.MustCompile(`(?s)^\+\+\+[ \t]*\n(?:(.*?)\n)?\+\+\+[ \t]*(?:\n|$)`)
+
+/*
+match the start of a json object, which opens with a key or is empty.
+content that starts with a shortcode or other braces is not json
+e.g.
+{"title": "my post"}
+*/
+var jsonRegex = regexp.MustCompile(`^\{\s*["}]`)
+
+var yamlLineRegex = regexp.MustCompile(`line (\d+): `)
+
+// ParseError is a front matter error at a position in the content file.
+// Line and Column are 1 based. A Column of 0 means the column is unknown.
+type ParseError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Err)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+type frontMatter struct {
+	format string
+	raw    []byte
+	// the line of the content file that raw starts on
+	line int
+}
+
+// normalize strips a leading byte order mark and converts CRLF line endings
+func normalize(content []byte) []byte {
+	content = bytes.TrimPrefix(content, bom)
+	return bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+}
+
+// position converts a byte offset of b into a 1 based line and column
+func position(b []byte, offset int) (int, int) {
+	if offset > len(b) {
+		offset = len(b)
+	}
+	before := b[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := offset - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// splitFrontMatter separates the front matter from the body of normalized content.
+// it reports false if there is no front matter.
+func splitFrontMatter(content []byte) (frontMatter, []byte, bool, error) {
+	fenced := []struct {
+		format string
+		re     *regexp.Regexp
+	}{{FormatYAML, yamlRegex}, {FormatTOML, tomlRegex}}
+	for _, f := range fenced {
+		m := f.re.FindSubmatchIndex(content)
+		if m == nil {
+			continue
+		}
+		fm := frontMatter{format: f.format, line: 2}
+		if m[2] >= 0 {
+			fm.raw = content[m[2]:m[3]]
+		}
+		return fm, content[m[1]:], true, nil
+	}
+	if jsonRegex.Match(content) {
+		var raw json.RawMessage
+		dec := json.NewDecoder(bytes.NewReader(content))
+		if err := dec.Decode(&raw); err != nil {
+			return frontMatter{}, nil, false, jsonError(err, content, 1)
+		}
+		end := int(dec.InputOffset())
+		return frontMatter{format: FormatJSON, raw: content[:end], line: 1}, bytes.TrimPrefix(content[end:], []byte("\n")), true, nil
+	}
+	return frontMatter{}, nil, false, nil
+}
+
+// knownKeys are the front matter keys that map to a field of Metadata
+var knownKeys = func() map[string]bool {
+	keys := map[string]bool{}
+	t := reflect.TypeOf(Metadata{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			keys[name] = true
+		}
+	}
+	return keys
+}()
+
+// IsKey reports whether key is a front matter key of Metadata rather than a custom field
+func IsKey(key string) bool {
+	return knownKeys[key]
+}
+
+func (fm frontMatter) decode(m *Metadata) error {
+	var err error
+	params := map[string]any{}
+	switch fm.format {
+	case FormatYAML:
+		err = decodeYAML(fm, m)
+		if err == nil {
+			err = yaml.Unmarshal(fm.raw, &params)
+		}
+	case FormatTOML:
+		err = decodeTOML(fm, m)
+		if err == nil {
+			_, err = toml.Decode(string(fm.raw), &params)
+		}
+	case FormatJSON:
+		err = decodeJSON(fm, m)
+		if err == nil {
+			err = json.Unmarshal(fm.raw, &params)
+		}
+	default:
+		return fmt.Errorf("unknown front matter format: %s", fm.format)
+	}
+	if err != nil {
+		return err
+	}
+	m.Lines = make(map[string]int, len(params))
+	for key := range params {
+		m.Lines[key] = fm.keyLine(key)
+		if knownKeys[key] {
+			delete(params, key)
+		}
+	}
+	if len(params) > 0 {
+		m.Params = params
+	}
+	return nil
+}
+
+// keyLine finds the line of the content file that key is set on.
+// it falls back to the first line of the front matter.
+func (fm frontMatter) keyLine(key string) int {
+	for i, line := range strings.Split(string(fm.raw), "\n") {
+		line = strings.TrimLeft(line, " \t{,")
+		line = strings.TrimLeft(line, `"'`)
+		rest, ok := strings.CutPrefix(line, key)
+		if !ok {
+			continue
+		}
+		rest = strings.TrimLeft(strings.TrimLeft(rest, `"'`), " \t")
+		if strings.HasPrefix(rest, ":") || strings.HasPrefix(rest, "=") {
+			return fm.line + i
+		}
+	}
+	return fm.line
+}
+
+func decodeYAML(fm frontMatter, m *Metadata) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(fm.raw, &doc); err != nil {
+		match := yamlLineRegex.FindStringSubmatchIndex(err.Error())
+		if match == nil {
+			return err
+		}
+		msg := err.Error()
+		line, _ := strconv.Atoi(msg[match[2]:match[3]])
+		return &ParseError{Line: fm.line + line - 1, Err: errors.New(msg[match[1]:])}
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return &ParseError{Line: fm.line + root.Line - 1, Column: root.Column, Err: errors.New("front matter must be a mapping")}
+	}
+	err := root.Decode(m)
+	if err == nil {
+		return nil
+	}
+	// decode key by key to find which value failed
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		v := root.Content[i+1]
+		pair := &yaml.Node{Kind: yaml.MappingNode, Tag: root.Tag, Content: root.Content[i : i+2]}
+		var probe Metadata
+		if perr := pair.Decode(&probe); perr != nil {
+			msg := perr.Error()
+			var typeErr *yaml.TypeError
+			if errors.As(perr, &typeErr) && len(typeErr.Errors) > 0 {
+				msg = yamlLineRegex.ReplaceAllString(typeErr.Errors[0], "")
+			}
+			return &ParseError{Line: fm.line + v.Line - 1, Column: v.Column, Err: errors.New(msg)}
+		}
+	}
+	return err
+}
+
+func decodeTOML(fm frontMatter, m *Metadata) error {
+	_, err := toml.Decode(string(fm.raw), m)
+	var perr toml.ParseError
+	if errors.As(err, &perr) {
+		return &ParseError{Line: fm.line + perr.Position.Line - 1, Column: perr.Position.Col, Err: errors.New(perr.Message)}
+	}
+	return err
+}
+
+func decodeJSON(fm frontMatter, m *Metadata) error {
+	return jsonError(json.Unmarshal(fm.raw, m), fm.raw, fm.line)
+}
+
+// jsonError adds the position to err if the json error includes an offset
+func jsonError(err error, b []byte, startLine int) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var offset int64
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return err
+	}
+	line, col := position(b, int(offset))
+	return &ParseError{Line: startLine + line - 1, Column: col, Err: err}
+}
@@ -0,0 +1,191 @@
+package metadata
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseMetadata(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantTitle string
+		wantTags  []string
+		wantBody  string
+		// the line of content that the body starts on
+		wantBodyLine int
+		wantLines    map[string]int
+	}{
+		{
+			name:         "yaml",
+			content:      "---\ntitle: Hello\ntags: [a, b]\n---\nbody\n",
+			wantTitle:    "Hello",
+			wantTags:     []string{"a", "b"},
+			wantBody:     "body\n",
+			wantBodyLine: 5,
+			wantLines:    map[string]int{"title": 2, "tags": 3},
+		},
+		{
+			name:         "toml",
+			content:      "+++\ntitle = \"Hello\"\ntags = [\"a\"]\n+++\nbody\n",
+			wantTitle:    "Hello",
+			wantTags:     []string{"a"},
+			wantBody:     "body\n",
+			wantBodyLine: 5,
+			wantLines:    map[string]int{"title": 2, "tags": 3},
+		},
+		{
+			name:         "json",
+			content:      "{\n  \"title\": \"Hello\",\n  \"tags\": [\"a\"]\n}\nbody\n",
+			wantTitle:    "Hello",
+			wantTags:     []string{"a"},
+			wantBody:     "body\n",
+			wantBodyLine: 5,
+			wantLines:    map[string]int{"title": 2, "tags": 3},
+		},
+		{
+			name:         "json on one line",
+			content:      "{\"title\": \"Hello\"}\nbody\n",
+			wantTitle:    "Hello",
+			wantBody:     "body\n",
+			wantBodyLine: 2,
+			wantLines:    map[string]int{"title": 1},
+		},
+		{
+			name:         "empty json",
+			content:      "{}\nbody\n",
+			wantBody:     "body\n",
+			wantBodyLine: 2,
+			wantLines:    map[string]int{},
+		},
+		{
+			name:         "json followed by a shortcode",
+			content:      "{ \"title\": \"Hello\" }\n{{< figure src=\"/static/a.png\" >}}\n",
+			wantTitle:    "Hello",
+			wantBody:     "{{< figure src=\"/static/a.png\" >}}\n",
+			wantBodyLine: 2,
+			wantLines:    map[string]int{"title": 1},
+		},
+		{
+			name:         "byte order mark",
+			content:      "\xef\xbb\xbf---\ntitle: Hello\n---\nbody\n",
+			wantTitle:    "Hello",
+			wantBody:     "body\n",
+			wantBodyLine: 4,
+			wantLines:    map[string]int{"title": 2},
+		},
+		{
+			name:         "crlf",
+			content:      "---\r\ntitle: Hello\r\n---\r\nbody\r\nmore\r\n",
+			wantTitle:    "Hello",
+			wantBody:     "body\nmore\n",
+			wantBodyLine: 4,
+			wantLines:    map[string]int{"title": 2},
+		},
+		{
+			name:         "byte order mark and crlf",
+			content:      "\xef\xbb\xbf+++\r\ntitle = \"Hello\"\r\n+++\r\nbody\r\n",
+			wantTitle:    "Hello",
+			wantBody:     "body\n",
+			wantBodyLine: 4,
+			wantLines:    map[string]int{"title": 2},
+		},
+		{
+			name:         "empty",
+			content:      "---\n---\nbody",
+			wantBody:     "body",
+			wantBodyLine: 3,
+			wantLines:    map[string]int{},
+		},
+		{
+			name:         "no body",
+			content:      "---\ntitle: Hello\n---",
+			wantTitle:    "Hello",
+			wantBody:     "",
+			wantBodyLine: 3,
+			wantLines:    map[string]int{"title": 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, body, bodyLine, err := parseMetadata([]byte(tt.content), time.UTC)
+			if err != nil {
+				t.Fatalf("parseMetadata() error = %v", err)
+			}
+			if m.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", m.Title, tt.wantTitle)
+			}
+			if !slices.Equal(m.Tags, tt.wantTags) {
+				t.Errorf("tags = %q, want %q", m.Tags, tt.wantTags)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+			if bodyLine != tt.wantBodyLine {
+				t.Errorf("body line = %d, want %d", bodyLine, tt.wantBodyLine)
+			}
+			if len(m.Lines) != len(tt.wantLines) {
+				t.Errorf("lines = %v, want %v", m.Lines, tt.wantLines)
+			}
+			for key, line := range tt.wantLines {
+				if m.Lines[key] != line {
+					t.Errorf("line of %s = %d, want %d", key, m.Lines[key], line)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMetadataParams(t *testing.T) {
+	m, _, _, err := parseMetadata([]byte("---\ntitle: Hello\nmood: happy\n---\n"), time.UTC)
+	if err != nil {
+		t.Fatalf("parseMetadata() error = %v", err)
+	}
+	if len(m.Params) != 1 || m.Params["mood"] != "happy" {
+		t.Errorf("params = %v, want only mood", m.Params)
+	}
+}
+
+func TestParseMetadataErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantLine int
+	}{
+		{"yaml syntax", "---\ntitle: Hello\n\tbad: x\n---\n", 3},
+		{"yaml type", "---\ntitle: Hello\ncreated: yesterday\n---\n", 3},
+		{"yaml not a mapping", "---\n- a\n---\n", 2},
+		{"toml syntax", "+++\ntitle = \"Hello\"\ntags = \n+++\n", 3},
+		{"json syntax", "{\n  \"title\": \"Hello\",\n  \"tags\": [\"a\",]\n}\n", 3},
+		{"crlf", "---\r\ntitle: Hello\r\ncreated: yesterday\r\n---\r\n", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := parseMetadata([]byte(tt.content), time.UTC)
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("parseMetadata() error = %v, want a ParseError", err)
+			}
+			if perr.Line != tt.wantLine {
+				t.Errorf("line = %d, want %d (%v)", perr.Line, tt.wantLine, err)
+			}
+		})
+	}
+}
+
+func TestParseMetadataNoFrontMatter(t *testing.T) {
+	for _, content := range []string{
+		"",
+		"just a body\n",
+		"--\ntitle: Hello\n--\n",
+		// shortcodes and other braces are not json front matter
+		"{{< figure src=\"/static/a.png\" >}}\nbody\n",
+		"{ not json }\n",
+	} {
+		if _, _, _, err := parseMetadata([]byte(content), time.UTC); !errors.Is(err, ErrNoMetadata) {
+			t.Errorf("parseMetadata(%q) error = %v, want ErrNoMetadata", content, err)
+		}
+	}
+}
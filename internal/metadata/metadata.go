@@ -1,34 +1,153 @@
 package metadata
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"regexp"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+var ErrNoMetadata = errors.New("no metadata found")
+
 /*
-match everything inbetween the '---' pairs
-e.g.
----
-this is matched content
----
+unzoned holds the front matter dates that do not specify a timezone while they are decoded.
+the decoders can't be given the timezone of the site, so parseMetadata moves them to it afterwards.
 */
-var metadataRegex = regexp.MustCompile(`(?s)^---\n(.*?)\n---`)
+var unzoned = time.FixedZone("unzoned", 0)
+
+// accepted date formats that carry no timezone, in order of preference
+var localDateLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
 
 type Date time.Time
 
+// ParseDate accepts RFC3339 timestamps, a date-time without a timezone or a plain date.
+// the latter two are read in loc.
+func ParseDate(s string, loc *time.Location) (Date, error) {
+	if parsed, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return Date(parsed), nil
+	}
+	for _, layout := range localDateLayouts {
+		if parsed, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return Date(parsed), nil
+		}
+	}
+	return Date{}, fmt.Errorf("invalid date %q: expected RFC3339 (2006-01-02T15:04:05Z07:00), a date-time (2006-01-02 15:04) or a date (2006-01-02)", s)
+}
+
 func (d *Date) UnmarshalYAML(v *yaml.Node) error {
-	parsed, err := time.Parse("2006-01-02", v.Value)
+	parsed, err := ParseDate(v.Value, unzoned)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// UnmarshalTOML accepts both toml dates and strings
+func (d *Date) UnmarshalTOML(v any) error {
+	switch v := v.(type) {
+	case time.Time:
+		// toml local dates and date-times have no offset
+		if strings.HasSuffix(v.Location().String(), "-local") {
+			v = time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), v.Nanosecond(), unzoned)
+		}
+		*d = Date(v)
+		return nil
+	case string:
+		parsed, err := ParseDate(v, unzoned)
+		if err != nil {
+			return err
+		}
+		*d = parsed
+		return nil
+	}
+	return fmt.Errorf("cannot unmarshal %T into a date", v)
+}
+
+func (d *Date) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseDate(s, unzoned)
 	if err != nil {
 		return err
 	}
-	*d = Date(parsed)
+	*d = parsed
+	return nil
+}
+
+// zoned moves a date decoded without a timezone to loc, keeping its wall clock
+func (d Date) zoned(loc *time.Location) Date {
+	t := time.Time(d)
+	if t.Location() != unzoned {
+		return d
+	}
+	return Date(time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc))
+}
+
+// Names is a front matter list of names that can also be written as a single name, e.g. menu: main or menu: [main, footer]
+type Names []string
+
+func (n *Names) UnmarshalYAML(v *yaml.Node) error {
+	if v.Kind == yaml.ScalarNode {
+		*n = Names{v.Value}
+		return nil
+	}
+	var names []string
+	if err := v.Decode(&names); err != nil {
+		return err
+	}
+	*n = names
+	return nil
+}
+
+func (n *Names) UnmarshalTOML(v any) error {
+	switch v := v.(type) {
+	case string:
+		*n = Names{v}
+		return nil
+	case []any:
+		names := Names{}
+		for _, name := range v {
+			s, ok := name.(string)
+			if !ok {
+				return fmt.Errorf("cannot unmarshal %T into a name", name)
+			}
+			names = append(names, s)
+		}
+		*n = names
+		return nil
+	}
+	return fmt.Errorf("cannot unmarshal %T into a list of names", v)
+}
+
+func (n *Names) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*n = Names{s}
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		return err
+	}
+	*n = names
 	return nil
 }
 
+// Equal reports whether both dates fall on the same day
 func (d Date) Equal(t Date) bool {
 	a := time.Time(d)
 	b := time.Time(t)
@@ -37,18 +156,128 @@ func (d Date) Equal(t Date) bool {
 	return ayr == byr && amo == bmo && aday == bday
 }
 
+func (d Date) IsZero() bool {
+	return time.Time(d).IsZero()
+}
+
+func (d Date) Before(t Date) bool {
+	return time.Time(d).Before(time.Time(t))
+}
+
+func (d Date) Format(layout string) string {
+	return time.Time(d).Format(layout)
+}
+
+// In returns the date in loc
+func (d Date) In(loc *time.Location) Date {
+	return Date(time.Time(d).In(loc))
+}
+
+// String returns a plain date for dates at midnight and a RFC3339 timestamp otherwise
 func (d Date) String() string {
-	return time.Time(d).Format("2006-01-02")
+	t := time.Time(d)
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339)
 }
 
 type Metadata struct {
-	ID          int
-	Filepath    string
-	Title       string   `yaml:"title"`
-	Author      string   `yaml:"author"`
-	Created     Date     `yaml:"created"`
-	LastUpdated Date     `yaml:"last_updated"`
-	Tags        []string `yaml:"tags"`
+	ID          int      `yaml:"-" toml:"-" json:"-"`
+	Filepath    string   `yaml:"-" toml:"-" json:"-"`
+	// the root relative url the content is served at
+	URL         string   `yaml:"-" toml:"-" json:"-"`
+	Title       string   `yaml:"title" toml:"title" json:"title"`
+	// the main author. the same as the first of Authors
+	Author      string   `yaml:"author" toml:"author" json:"author"`
+	Authors     []string `yaml:"authors" toml:"authors" json:"authors"`
+	Created     Date     `yaml:"created" toml:"created" json:"created"`
+	LastUpdated Date     `yaml:"last_updated" toml:"last_updated" json:"last_updated"`
+	Tags        []string `yaml:"tags" toml:"tags" json:"tags"`
+	// the name of the series the content is part of
+	Series string `yaml:"series" toml:"series" json:"series"`
+	// the position of the content in its series
+	SeriesOrder int `yaml:"series_order" toml:"series_order" json:"series_order"`
+	// a short plain text description shown in lists and feeds.
+	// when not set it is taken from the content when compiling
+	Summary string `yaml:"summary" toml:"summary" json:"summary"`
+	// the description in the page head and link previews. the summary is used when not set
+	Description string `yaml:"description" toml:"description" json:"description"`
+	// the image shown in link previews, e.g. /static/cover.png
+	Image string `yaml:"image" toml:"image" json:"image"`
+	// the number of words in the rendered content
+	WordCount int `yaml:"-" toml:"-" json:"-"`
+	// the estimated minutes it takes to read the content
+	ReadingTime int `yaml:"-" toml:"-" json:"-"`
+	// the menus the content is listed in and its position in them
+	Menu       Names `yaml:"menu" toml:"menu" json:"menu"`
+	MenuWeight int   `yaml:"menu_weight" toml:"menu_weight" json:"menu_weight"`
+	// the name of the layout the content is rendered with, e.g. bare
+	Layout string `yaml:"layout" toml:"layout" json:"layout"`
+	// any other front matter keys
+	Params map[string]any `yaml:"-" toml:"-" json:"-"`
+	// the line of the content file that each front matter key is on
+	Lines map[string]int `yaml:"-" toml:"-" json:"-"`
+}
+
+// Has reports whether key is set in the front matter
+func (m *Metadata) Has(key string) bool {
+	_, ok := m.Lines[key]
+	return ok
+}
+
+// Line returns the line of the content file that key is on.
+// keys that are not set are reported on the first line.
+func (m *Metadata) Line(key string) int {
+	if line, ok := m.Lines[key]; ok {
+		return line
+	}
+	return 1
+}
+
+// Param returns the custom front matter field or nil if it is not set
+func (m *Metadata) Param(key string) any {
+	return m.Params[key]
+}
+
+// Field returns the value of a front matter key, e.g. title or tags, falling back to the custom fields.
+// url, word_count and reading_time are also available.
+func (m *Metadata) Field(key string) any {
+	switch key {
+	case "url":
+		return m.URL
+	case "title":
+		return m.Title
+	case "author":
+		return m.Author
+	case "authors":
+		return m.Authors
+	case "created":
+		return m.Created
+	case "last_updated":
+		return m.LastUpdated
+	case "tags":
+		return m.Tags
+	case "series":
+		return m.Series
+	case "series_order":
+		return m.SeriesOrder
+	case "summary":
+		return m.Summary
+	case "description":
+		return m.Description
+	case "image":
+		return m.Image
+	case "word_count":
+		return m.WordCount
+	case "reading_time":
+		return m.ReadingTime
+	case "menu":
+		return []string(m.Menu)
+	case "menu_weight":
+		return m.MenuWeight
+	}
+	return m.Param(key)
 }
 
 func (m *Metadata) String() string {
@@ -66,13 +295,25 @@ func MetadataTemplate() string {
 	m := Metadata{
 		Title: "<title>",
 		Author: "<author>",
-		Created: Date(time.Now()),
-		LastUpdated: Date(time.Now()),
+		Created: Date(time.Now().Truncate(time.Second)),
+		LastUpdated: Date(time.Now().Truncate(time.Second)),
 		Tags: []string{"list", "of", "tags"},
 	}
 	return m.String()
 }
 
+// SetAuthors keeps Author and Authors in sync.
+// a single author is the only entry of Authors, and the first of Authors is the Author.
+// when the front matter sets both, authors wins and author is ignored.
+func (m *Metadata) SetAuthors() {
+	if len(m.Authors) == 0 && m.Author != "" {
+		m.Authors = []string{m.Author}
+	}
+	if len(m.Authors) > 0 {
+		m.Author = m.Authors[0]
+	}
+}
+
 func (m *Metadata) ContainsTag(tagName string) bool {
 	for _, tag := range m.Tags {
 		if tagName == tag {
@@ -82,27 +323,44 @@ func (m *Metadata) ContainsTag(tagName string) bool {
 	return false
 }
 
-func parseMetadata(content []byte) (Metadata, error) {
-	matches := metadataRegex.FindSubmatch(content)
-	if len(matches) < 2 {
-		return Metadata{}, fmt.Errorf("no metadata found. use this template:\n%s", MetadataTemplate())
+// parseMetadata reads the yaml (---), toml (+++) or json ({...}) front matter of content. dates without a timezone are read in loc.
+// it returns the metadata, the remaining body of the content and the 1 based line of content that the body starts on.
+func parseMetadata(content []byte, loc *time.Location) (Metadata, []byte, int, error) {
+	normalized := normalize(content)
+	fm, body, ok, err := splitFrontMatter(normalized)
+	if err != nil {
+		return Metadata{}, nil, 0, fmt.Errorf("unable to parse metadata: %w", err)
+	}
+	if !ok {
+		return Metadata{}, nil, 0, fmt.Errorf("%w. use this template:\n%s", ErrNoMetadata, MetadataTemplate())
 	}
 	var metadata Metadata
-	if err := yaml.Unmarshal(matches[1], &metadata); err != nil {
-		return Metadata{}, fmt.Errorf("unable to parse metadata: %w", err)
+	if err := fm.decode(&metadata); err != nil {
+		return Metadata{}, nil, 0, fmt.Errorf("unable to parse %s metadata: %w", fm.format, err)
 	}
-	return metadata, nil
+	metadata.Created, metadata.LastUpdated = metadata.Created.zoned(loc), metadata.LastUpdated.zoned(loc)
+	metadata.SetAuthors()
+	// the body is the end of the content
+	bodyLine := bytes.Count(normalized[:len(normalized)-len(body)], []byte("\n")) + 1
+	return metadata, body, bodyLine, nil
 }
 
-func ExtractFromFile(filepath string) (Metadata, error) {
+// SplitFile returns the metadata of the file along with the body that follows it and the line of the file the body starts on.
+// dates without a timezone are read in loc.
+func SplitFile(filepath string, loc *time.Location) (Metadata, []byte, int, error) {
 	content, err := os.ReadFile(filepath)
 	if err != nil {
-		return Metadata{}, err
+		return Metadata{}, nil, 0, err
 	}
-	m, err := parseMetadata(content)
+	m, body, bodyLine, err := parseMetadata(content, loc)
 	if err != nil {
-		return Metadata{}, fmt.Errorf("unable to extract metadata from file %s: %w", filepath, err)
+		return Metadata{}, nil, 0, fmt.Errorf("unable to extract metadata from file %s: %w", filepath, err)
 	}
 	m.Filepath = filepath
-	return m, nil
+	return m, body, bodyLine, nil
+}
+
+func ExtractFromFile(filepath string, loc *time.Location) (Metadata, error) {
+	m, _, _, err := SplitFile(filepath, loc)
+	return m, err
 }
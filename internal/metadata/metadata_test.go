@@ -0,0 +1,83 @@
+package metadata
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no timezone database: %v", err)
+	}
+	tests := []struct {
+		name    string
+		s       string
+		loc     *time.Location
+		want    time.Time
+		wantErr bool
+	}{
+		{"rfc3339 utc", "2024-03-10T12:30:00Z", ny, time.Date(2024, 3, 10, 12, 30, 0, 0, time.UTC), false},
+		{"rfc3339 offset", "2024-03-10T12:30:00+02:00", ny, time.Date(2024, 3, 10, 10, 30, 0, 0, time.UTC), false},
+		{"rfc3339 offset ignores loc", "2024-03-10T12:30:00-07:00", time.UTC, time.Date(2024, 3, 10, 19, 30, 0, 0, time.UTC), false},
+		{"rfc3339 fractional seconds", "2024-03-10T12:30:00.5Z", ny, time.Date(2024, 3, 10, 12, 30, 0, 5e8, time.UTC), false},
+		{"local date-time", "2024-03-10 12:30:00", ny, time.Date(2024, 3, 10, 12, 30, 0, 0, ny), false},
+		{"local date-time with a T", "2024-03-10T12:30", ny, time.Date(2024, 3, 10, 12, 30, 0, 0, ny), false},
+		{"local date-time after a dst change", "2024-03-11 12:30", ny, time.Date(2024, 3, 11, 16, 30, 0, 0, time.UTC), false},
+		{"local date-time in utc", "2024-03-10 12:30", time.UTC, time.Date(2024, 3, 10, 12, 30, 0, 0, time.UTC), false},
+		{"date", "2024-03-10", ny, time.Date(2024, 3, 10, 0, 0, 0, 0, ny), false},
+		{"not a date", "March 10", ny, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDate(tt.s, tt.loc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !time.Time(got).Equal(tt.want) {
+				t.Errorf("ParseDate() = %v, want %v", time.Time(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMetadataDates(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no timezone database: %v", err)
+	}
+	tests := []struct {
+		name    string
+		content string
+		want    time.Time
+		// the timezone of the parsed date, nil when only the instant matters
+		wantLoc *time.Location
+	}{
+		{"yaml date", "---\ncreated: 2024-03-10\n---\n", time.Date(2024, 3, 10, 0, 0, 0, 0, ny), ny},
+		{"yaml local date-time", "---\ncreated: 2024-03-10 12:30\n---\n", time.Date(2024, 3, 10, 12, 30, 0, 0, ny), ny},
+		{"yaml rfc3339 offset", "---\ncreated: 2024-03-10T12:30:00+02:00\n---\n", time.Date(2024, 3, 10, 10, 30, 0, 0, time.UTC), nil},
+		{"json local date-time", "{\n\"created\": \"2024-03-10 12:30\"\n}\n", time.Date(2024, 3, 10, 12, 30, 0, 0, ny), ny},
+		{"toml local date", "+++\ncreated = 2024-03-10\n+++\n", time.Date(2024, 3, 10, 0, 0, 0, 0, ny), ny},
+		{"toml local date-time", "+++\ncreated = 2024-03-10T12:30:00\n+++\n", time.Date(2024, 3, 10, 12, 30, 0, 0, ny), ny},
+		{"toml offset date-time", "+++\ncreated = 2024-03-10T12:30:00Z\n+++\n", time.Date(2024, 3, 10, 12, 30, 0, 0, time.UTC), nil},
+		{"toml string", "+++\ncreated = \"2024-03-10 12:30\"\n+++\n", time.Date(2024, 3, 10, 12, 30, 0, 0, ny), ny},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _, _, err := parseMetadata([]byte(tt.content), ny)
+			if err != nil {
+				t.Fatalf("parseMetadata() error = %v", err)
+			}
+			got := time.Time(m.Created)
+			if !got.Equal(tt.want) {
+				t.Errorf("created = %v, want %v", got, tt.want)
+			}
+			if tt.wantLoc != nil && got.Location() != tt.wantLoc {
+				t.Errorf("created is in %v, want %v", got.Location(), tt.wantLoc)
+			}
+			if !m.LastUpdated.IsZero() {
+				t.Errorf("last_updated = %v, want it unset", m.LastUpdated)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package paginate
+
+import "fmt"
+
+// Paginator describes one page of a list that is split over several pages
+type Paginator struct {
+	// the 1 based number of this page
+	Current int
+	// the number of pages
+	Total int
+	// the number of items on a full page
+	PageSize int
+	// the number of items over all pages
+	TotalItems int
+	// the url of the first page, e.g. /all/
+	BaseURL string
+	URL     string
+	// empty on the first page
+	PrevURL string
+	// empty on the last page
+	NextURL  string
+	FirstURL string
+	LastURL  string
+}
+
+// URL is the url of the nth page of the list at base. the first page is base itself, the rest are at base/page/n/
+func URL(base string, n int) string {
+	if n <= 1 {
+		return base
+	}
+	return fmt.Sprintf("%spage/%d/", base, n)
+}
+
+// Pages is the number of pages needed for totalItems. there is always at least one (possibly empty) page.
+// a pageSize of 0 or less puts everything on one page.
+func Pages(totalItems int, pageSize int) int {
+	if pageSize <= 0 || totalItems == 0 {
+		return 1
+	}
+	return (totalItems + pageSize - 1) / pageSize
+}
+
+// New returns the paginator of the current (1 based) page
+func New(baseURL string, totalItems int, pageSize int, current int) Paginator {
+	total := Pages(totalItems, pageSize)
+	p := Paginator{
+		Current:    current,
+		Total:      total,
+		PageSize:   pageSize,
+		TotalItems: totalItems,
+		BaseURL:    baseURL,
+		URL:        URL(baseURL, current),
+		FirstURL:   URL(baseURL, 1),
+		LastURL:    URL(baseURL, total),
+	}
+	if current > 1 {
+		p.PrevURL = URL(baseURL, current-1)
+	}
+	if current < total {
+		p.NextURL = URL(baseURL, current+1)
+	}
+	return p
+}
+
+// Valid reports whether the current page exists
+func (p Paginator) Valid() bool {
+	return p.Current >= 1 && p.Current <= p.Total
+}
+
+// Slice returns the items on the current page
+func Slice[T any](items []T, p Paginator) []T {
+	if p.PageSize <= 0 {
+		return items
+	}
+	start := (p.Current - 1) * p.PageSize
+	if start < 0 || start >= len(items) {
+		return []T{}
+	}
+	end := min(start+p.PageSize, len(items))
+	return items[start:end]
+}
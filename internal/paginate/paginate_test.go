@@ -0,0 +1,98 @@
+package paginate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPages(t *testing.T) {
+	tests := []struct {
+		name       string
+		totalItems int
+		pageSize   int
+		want       int
+	}{
+		{"empty", 0, 10, 1},
+		{"one item", 1, 10, 1},
+		{"full page", 10, 10, 1},
+		{"one over", 11, 10, 2},
+		{"last page full", 20, 10, 2},
+		{"no page size", 25, 0, 1},
+		{"negative page size", 25, -1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Pages(tt.totalItems, tt.pageSize); got != tt.want {
+				t.Errorf("Pages(%d, %d) = %d, want %d", tt.totalItems, tt.pageSize, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name       string
+		totalItems int
+		current    int
+		want       Paginator
+	}{
+		{"empty", 0, 1, Paginator{Current: 1, Total: 1, PageSize: 2, TotalItems: 0, BaseURL: "/all/", URL: "/all/", FirstURL: "/all/", LastURL: "/all/"}},
+		{"first", 5, 1, Paginator{Current: 1, Total: 3, PageSize: 2, TotalItems: 5, BaseURL: "/all/", URL: "/all/", NextURL: "/all/page/2/", FirstURL: "/all/", LastURL: "/all/page/3/"}},
+		{"middle", 5, 2, Paginator{Current: 2, Total: 3, PageSize: 2, TotalItems: 5, BaseURL: "/all/", URL: "/all/page/2/", PrevURL: "/all/", NextURL: "/all/page/3/", FirstURL: "/all/", LastURL: "/all/page/3/"}},
+		{"last", 5, 3, Paginator{Current: 3, Total: 3, PageSize: 2, TotalItems: 5, BaseURL: "/all/", URL: "/all/page/3/", PrevURL: "/all/page/2/", FirstURL: "/all/", LastURL: "/all/page/3/"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New("/all/", tt.totalItems, 2, tt.current); got != tt.want {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		name       string
+		totalItems int
+		current    int
+		want       bool
+	}{
+		{"empty first", 0, 1, true},
+		{"last", 5, 3, true},
+		{"past the last", 5, 4, false},
+		{"zero", 5, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New("/", tt.totalItems, 2, tt.current).Valid(); got != tt.want {
+				t.Errorf("Valid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlice(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	tests := []struct {
+		name     string
+		items    []int
+		pageSize int
+		current  int
+		want     []int
+	}{
+		{"empty", []int{}, 2, 1, []int{}},
+		{"first", items, 2, 1, []int{1, 2}},
+		{"last partial", items, 2, 3, []int{5}},
+		{"last full", items[:4], 2, 2, []int{3, 4}},
+		{"past the last", items, 2, 4, []int{}},
+		{"no page size", items, 0, 1, items},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New("/", len(tt.items), tt.pageSize, tt.current)
+			if got := Slice(tt.items, p); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Slice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,19 +1,15 @@
 package pandoc
 
 import (
-	_ "embed"
-	"html/template"
+	"bytes"
 	"os/exec"
 	"path/filepath"
 )
 
-//go:embed layout.html
-var defaultLayout string
-
-var DefaultLayout = template.Must(template.New("default_layout").Parse(defaultLayout))
-
-func PandocToHTML(filepath string) (string, error) {
-	cmd := exec.Command("pandoc", filepath, "--to", "html")
+// PandocToHTML converts markdown (with its front matter already removed) to html
+func PandocToHTML(markdown []byte) (string, error) {
+	cmd := exec.Command("pandoc", "--from", "markdown", "--to", "html")
+	cmd.Stdin = bytes.NewReader(markdown)
 	fbyte, err := cmd.Output()
 	if err != nil {
 		return "", err
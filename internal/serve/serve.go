@@ -5,60 +5,147 @@ import (
 	"fmt"
 	"html/template"
 	"net/http"
+	"net/url"
 	"path/filepath"
+	"regexp"
+	"strconv"
 
+	"github.com/jcocozza/jbf/internal/config"
+	"github.com/jcocozza/jbf/internal/dal"
 	"github.com/jcocozza/jbf/internal/metadata"
-	"github.com/jcocozza/jbf/internal/pandoc"
+	"github.com/jcocozza/jbf/internal/paginate"
 	"github.com/jcocozza/jbf/internal/service"
+	"github.com/jcocozza/jbf/internal/theme"
+	"gopkg.in/yaml.v3"
 )
 
+const allURL = "/all/"
+
+// matches the pages of /all after the first, e.g. /all/page/2/
+var allPageRegex = regexp.MustCompile(`^/all/page/(\d+)/?$`)
+
 type Handler struct {
 	s              *service.Service
 	htmlContentDir string
 	baseContentDir string
+	cfg            config.Config
+	theme          *theme.Theme
+}
+
+/*
+parseQuery turns the url query into a metadata query.
+'sort' and 'order' control sorting, front matter keys and the custom fields in fields filter and everything else (e.g. utm_source) is ignored.
+e.g. /all?series=intro&sort=series_order&order=asc
+*/
+func parseQuery(values url.Values, fields map[string]bool) dal.Query {
+	q := dal.Query{
+		Params: map[string]any{},
+		SortBy: values.Get("sort"),
+		Asc:    values.Get("order") == "asc",
+	}
+	for key := range values {
+		if key == "sort" || key == "order" || !(metadata.IsKey(key) || fields[key]) {
+			continue
+		}
+		// read the value the same way it would be read in front matter so that e.g. 'true' and '3' match
+		var v any
+		if err := yaml.Unmarshal([]byte(values.Get(key)), &v); err != nil || v == nil {
+			v = values.Get(key)
+		}
+		q.Params[key] = v
+	}
+	return q
 }
 
-func (h *Handler) HandleAllPage(w http.ResponseWriter, r *http.Request) {
-	ml, err := h.s.ListContentByDate()
+// fields are the custom fields that the content has, along with the fields that are computed when compiling
+func (h *Handler) fields() (map[string]bool, error) {
+	all, err := h.s.ListContent(dal.Query{})
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]bool{"url": true, "word_count": true, "reading_time": true}
+	for _, m := range all {
+		for key := range m.Params {
+			fields[key] = true
+		}
+	}
+	return fields, nil
+}
+
+// HandleAllPage serves the nth page of /all
+func (h *Handler) HandleAllPage(w http.ResponseWriter, r *http.Request, n int) {
+	fields, err := h.fields()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	all, err := h.s.ListContent(parseQuery(r.URL.Query(), fields))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ml := []metadata.Metadata{}
+	for _, m := range all {
+		if m.URL != "/" {
+			ml = append(ml, m)
+		}
+	}
+	layout, err := h.theme.Layout(theme.List)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	site, err := h.s.Site(h.cfg, allURL)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	if len(ml) == 0 {
-		var data = struct{ Content string}{ Content: "nothing to see here."}
-		pandoc.DefaultLayout.Execute(w, data)
+		var data = service.LayoutData{Content: "nothing to see here.", Name: h.cfg.Name, Site: site}
+		layout.Execute(w, data)
 		return
 	}
+	pager := paginate.New(allURL, len(ml), h.cfg.Paginate, n)
+	if !pager.Valid() {
+		http.NotFound(w, r)
+		return
+	}
+	if r.URL.RawQuery != "" {
+		// keep the filters when moving between pages
+		for _, u := range []*string{&pager.URL, &pager.PrevURL, &pager.NextURL, &pager.FirstURL, &pager.LastURL} {
+			if *u != "" {
+				*u += "?" + r.URL.RawQuery
+			}
+		}
+	}
+	ml = paginate.Slice(ml, pager)
 
 	var currT metadata.Date = ml[0].Created
-	s := ml[0].Created.String() + " <ul>"
+	s := ml[0].Created.Format(h.cfg.DateFormat) + " <ul>"
 	for _, m := range ml {
-		op, err := service.GetOutputPath(m.Filepath, h.baseContentDir, h.htmlContentDir)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		if op == "index.html" {
-			continue
+		item := fmt.Sprintf("<li><a href=\"%s\">%s</a>", template.HTMLEscapeString(m.URL), template.HTMLEscapeString(m.Title))
+		if m.Summary != "" {
+			item += fmt.Sprintf("<p class=\"summary\">%s</p>", template.HTMLEscapeString(m.Summary))
 		}
+		item += "</li>"
 		if currT.Equal(m.Created) {
-			s += fmt.Sprintf("<li><a href=%s>%s</a></li>", op, m.Title)
+			s += item
 			continue
 		}
 		s += " </ul>"
 		currT = m.Created
-		s += m.Created.String() + " <ul>"
-		s += fmt.Sprintf("<li><a href=%s>%s</a></li>", op, m.Title)
+		s += m.Created.Format(h.cfg.DateFormat) + " <ul>"
+		s += item
 	}
 
-	var data = struct {
-		Content template.HTML
-		Name    string
-	}{
-		Content: template.HTML(s),
-		Name:    "foo bar",
-	}
-	err = pandoc.DefaultLayout.Execute(w, data)
+	var data = service.LayoutData{
+		Content:   template.HTML(s),
+		Name:      h.cfg.Name,
+		Site:      site,
+		Page:      metadata.Metadata{Title: "All", URL: pager.URL},
+		Paginator: &pager,
+	}
+	err = layout.Execute(w, data)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
@@ -66,7 +153,12 @@ func (h *Handler) HandleAllPage(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) handleFiles(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path == "/all" || r.URL.Path == "/all/" {
-		h.HandleAllPage(w, r)
+		h.HandleAllPage(w, r, 1)
+		return
+	}
+	if match := allPageRegex.FindStringSubmatch(r.URL.Path); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		h.HandleAllPage(w, r, n)
 		return
 	}
 	http.FileServer(http.Dir(h.htmlContentDir)).ServeHTTP(w, r)
@@ -80,11 +172,13 @@ func router(h *Handler) http.Handler {
 	return mux
 }
 
-func Server(s *service.Service, htmlContentDir string, baseContentDir string) {
+func Server(s *service.Service, htmlContentDir string, baseContentDir string, cfg config.Config, t *theme.Theme) {
 	h := &Handler{
 		s:              s,
 		htmlContentDir: htmlContentDir,
 		baseContentDir: baseContentDir,
+		cfg:            cfg,
+		theme:          t,
 	}
 	r := router(h)
 	err := http.ListenAndServe(":55000", r) // TODO: allow this port to be specified
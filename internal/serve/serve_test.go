@@ -0,0 +1,35 @@
+package serve
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/jcocozza/jbf/internal/dal"
+)
+
+func TestParseQuery(t *testing.T) {
+	fields := map[string]bool{"featured": true, "weight": true}
+	tests := []struct {
+		name  string
+		query string
+		want  dal.Query
+	}{
+		{"empty", "", dal.Query{Params: map[string]any{}}},
+		{"front matter key", "series=intro", dal.Query{Params: map[string]any{"series": "intro"}}},
+		{"custom field", "featured=true&weight=3", dal.Query{Params: map[string]any{"featured": true, "weight": 3}}},
+		{"sort", "sort=series_order&order=asc", dal.Query{Params: map[string]any{}, SortBy: "series_order", Asc: true}},
+		{"unknown keys", "utm_source=feed&page=2&series=intro", dal.Query{Params: map[string]any{"series": "intro"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := parseQuery(values, fields); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
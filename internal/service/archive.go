@@ -0,0 +1,111 @@
+package service
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jcocozza/jbf/internal/metadata"
+	"github.com/jcocozza/jbf/internal/theme"
+)
+
+const archiveURL = "/archive/"
+
+type archiveMonth struct {
+	month time.Month
+	posts []metadata.Metadata
+}
+
+type archiveYear struct {
+	year   int
+	posts  []metadata.Metadata
+	months []*archiveMonth
+}
+
+func (y *archiveYear) url() string {
+	return fmt.Sprintf("%s%d/", archiveURL, y.year)
+}
+
+func (y *archiveYear) monthURL(m *archiveMonth) string {
+	return fmt.Sprintf("%s%02d/", y.url(), int(m.month))
+}
+
+// groupByMonth groups posts by the year and month they were created, newest first.
+// all must be ordered newest first, like ReadAllMetadata.
+func groupByMonth(all []metadata.Metadata) []*archiveYear {
+	years := []*archiveYear{}
+	for _, m := range all {
+		if m.URL == "/" || m.Created.IsZero() {
+			continue
+		}
+		t := time.Time(m.Created)
+		if len(years) == 0 || years[len(years)-1].year != t.Year() {
+			years = append(years, &archiveYear{year: t.Year()})
+		}
+		y := years[len(years)-1]
+		y.posts = append(y.posts, m)
+		if len(y.months) == 0 || y.months[len(y.months)-1].month != t.Month() {
+			y.months = append(y.months, &archiveMonth{month: t.Month()})
+		}
+		mo := y.months[len(y.months)-1]
+		mo.posts = append(mo.posts, m)
+	}
+	return years
+}
+
+// monthsHTML lists the months of the year with their post counts
+func monthsHTML(y *archiveYear) string {
+	var b strings.Builder
+	b.WriteString("<ul class=\"archive\">\n")
+	for _, m := range y.months {
+		b.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a> (%d)</li>\n", y.monthURL(m), m.month, len(m.posts)))
+	}
+	b.WriteString("</ul>\n")
+	return b.String()
+}
+
+/*
+writeArchivePages creates
+  - /archive/ with the post counts of every year and month
+  - /archive/<year>/ with the months and posts of the year
+  - /archive/<year>/<month>/ with the posts of the month
+*/
+func (s *Service) writeArchivePages(outputDir string, all []metadata.Metadata, cfg Config) error {
+	years := groupByMonth(all)
+
+	var b strings.Builder
+	b.WriteString("<h1>Archive</h1>\n<ul class=\"archive\">\n")
+	for _, y := range years {
+		b.WriteString(fmt.Sprintf("<li><a href=\"%s\">%d</a> (%d)\n%s</li>\n", y.url(), y.year, len(y.posts), monthsHTML(y)))
+	}
+	b.WriteString("</ul>\n")
+	data := LayoutData{
+		Content: template.HTML(b.String()),
+		Name:    cfg.Name,
+		Page:    metadata.Metadata{Title: "Archive", URL: archiveURL},
+	}
+	p := filepath.Join(outputDir, filepath.FromSlash(archiveURL), "index.html")
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	if err := writePage(p, theme.List, data, cfg); err != nil {
+		return err
+	}
+
+	for _, y := range years {
+		yearMd := metadata.Metadata{Title: fmt.Sprintf("%d (%d)", y.year, len(y.posts)), URL: y.url()}
+		if err := s.writeList(outputDir, theme.List, yearMd, monthsHTML(y), y.posts, cfg); err != nil {
+			return err
+		}
+		for _, m := range y.months {
+			monthMd := metadata.Metadata{Title: fmt.Sprintf("%s %d (%d)", m.month, y.year, len(m.posts)), URL: y.monthURL(m)}
+			if err := s.writeList(outputDir, theme.List, monthMd, "", m.posts, cfg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
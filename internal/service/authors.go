@@ -0,0 +1,96 @@
+package service
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jcocozza/jbf/internal/author"
+	"github.com/jcocozza/jbf/internal/metadata"
+	"github.com/jcocozza/jbf/internal/theme"
+)
+
+// authorIntro renders the profile of the author shown above their posts
+func authorIntro(a author.Author) string {
+	var b strings.Builder
+	if a.Avatar != "" {
+		b.WriteString(fmt.Sprintf("<img class=\"avatar\" src=\"%s\" alt=\"%s\" />\n", template.HTMLEscapeString(a.Avatar), template.HTMLEscapeString(a.Name)))
+	}
+	if a.Bio != "" {
+		b.WriteString(fmt.Sprintf("<p class=\"bio\">%s</p>\n", template.HTMLEscapeString(a.Bio)))
+	}
+	if len(a.Links) > 0 {
+		b.WriteString("<ul class=\"author-links\">\n")
+		for _, l := range a.Links {
+			b.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a></li>\n", template.HTMLEscapeString(l.URL), template.HTMLEscapeString(l.Name)))
+		}
+		b.WriteString("</ul>\n")
+	}
+	return b.String()
+}
+
+/*
+writeAuthorPages creates a list page and a feed for every author at /authors/<slug>/ and an index of all the authors at /authors/.
+every author in the authors file gets a page, even without any posts.
+*/
+func (s *Service) writeAuthorPages(outputDir string, all []metadata.Metadata, cfg Config) error {
+	authors := map[string]author.Author{}
+	byAuthor := map[string][]metadata.Metadata{}
+	for _, a := range cfg.Authors {
+		authors[a.Slug] = a
+	}
+	for _, m := range feedPosts(all) {
+		for _, a := range cfg.Authors.ResolveAll(m.Authors) {
+			if other, ok := authors[a.Slug]; ok && !strings.EqualFold(other.Name, a.Name) {
+				return fmt.Errorf("%s: authors %q and %q would both be written to %s, add one of them to the authors file", m.Filepath, other.Name, a.Name, a.URL())
+			}
+			authors[a.Slug] = a
+			byAuthor[a.Slug] = append(byAuthor[a.Slug], m)
+		}
+	}
+	if len(authors) == 0 {
+		return nil
+	}
+	slugs := make([]string, 0, len(authors))
+	for slug := range authors {
+		slugs = append(slugs, slug)
+	}
+	sort.Slice(slugs, func(i, j int) bool {
+		return strings.ToLower(authors[slugs[i]].Name) < strings.ToLower(authors[slugs[j]].Name)
+	})
+
+	var b strings.Builder
+	b.WriteString("<h1>Authors</h1>\n<ul class=\"authors\">\n")
+	for _, slug := range slugs {
+		a := authors[slug]
+		b.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a> (%d)</li>\n", a.URL(), template.HTMLEscapeString(a.Name), len(byAuthor[slug])))
+	}
+	b.WriteString("</ul>\n")
+	data := LayoutData{
+		Content: template.HTML(b.String()),
+		Name:    cfg.Name,
+		Page:    metadata.Metadata{Title: "Authors", URL: author.IndexURL()},
+	}
+	p := filepath.Join(outputDir, filepath.FromSlash(author.IndexURL()), "index.html")
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	if err := writePage(p, theme.List, data, cfg); err != nil {
+		return err
+	}
+
+	for _, slug := range slugs {
+		a := authors[slug]
+		pageMd := metadata.Metadata{Title: a.Name, URL: a.URL()}
+		if err := s.writeList(outputDir, theme.List, pageMd, authorIntro(a), byAuthor[slug], cfg); err != nil {
+			return err
+		}
+		if err := writeFeed(outputDir, cfg.Name+" - "+a.Name, a.URL(), byAuthor[slug], cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"encoding/xml"
+	"strings"
+	"time"
+
+	"github.com/jcocozza/jbf/internal/fsutil"
+	"github.com/jcocozza/jbf/internal/metadata"
+)
+
+const feedFile = "index.xml"
+
+// the dublin core namespace of dc:creator
+const dcNamespace = "http://purl.org/dc/elements/1.1/"
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title   string `xml:"title"`
+	Link    string `xml:"link"`
+	GUID    string `xml:"guid"`
+	PubDate string `xml:"pubDate"`
+	// the names of the authors. rss's own author element has to be an email address
+	Creators    []string `xml:"dc:creator"`
+	Description string   `xml:"description"`
+}
+
+// absURL joins the site base url with a url. urls that are already absolute are left alone
+func absURL(baseURL string, u string) string {
+	if isAbs(u) {
+		return u
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(u, "/")
+}
+
+/*
+writeFeed writes the newest posts as an rss feed to the index.xml of the directory at url.
+e.g. the feed of / is /index.xml
+posts must be ordered newest first.
+*/
+func writeFeed(outputDir string, title string, url string, posts []metadata.Metadata, cfg Config) error {
+	if cfg.FeedItems > 0 && len(posts) > cfg.FeedItems {
+		posts = posts[:cfg.FeedItems]
+	}
+	channel := rssChannel{
+		Title:       title,
+		Link:        absURL(cfg.BaseURL, url),
+		Description: title,
+	}
+	for _, m := range posts {
+		link := absURL(cfg.BaseURL, m.URL)
+		names := []string{}
+		for _, a := range cfg.Authors.ResolveAll(m.Authors) {
+			names = append(names, a.Name)
+		}
+		channel.Items = append(channel.Items, rssItem{
+			Title:       m.Title,
+			Link:        link,
+			GUID:        link,
+			PubDate:     time.Time(m.Created).Format(time.RFC1123Z),
+			Creators:    names,
+			Description: m.Summary,
+		})
+	}
+	// the last time the content of the feed changed rather than the time of the compile, so that compiling again gives the same feed
+	var lastBuild time.Time
+	for _, m := range posts {
+		for _, d := range []metadata.Date{m.Created, m.LastUpdated} {
+			if time.Time(d).After(lastBuild) {
+				lastBuild = time.Time(d)
+			}
+		}
+	}
+	if !lastBuild.IsZero() {
+		channel.LastBuildDate = lastBuild.Format(time.RFC1123Z)
+	}
+	b, err := xml.MarshalIndent(rss{Version: "2.0", DC: dcNamespace, Channel: channel}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteReadOnly(outputFile(outputDir, url, feedFile), []byte(xml.Header+string(b)+"\n"))
+}
+
+// feedPosts is the content that shows up in feeds, which leaves out the home page
+func feedPosts(all []metadata.Metadata) []metadata.Metadata {
+	posts := []metadata.Metadata{}
+	for _, m := range all {
+		if m.URL != "/" {
+			posts = append(posts, m)
+		}
+	}
+	return posts
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jcocozza/jbf/internal/config"
+	"github.com/jcocozza/jbf/internal/metadata"
+)
+
+func TestWriteFeed(t *testing.T) {
+	outputDir := t.TempDir()
+	posts := []metadata.Metadata{
+		{URL: "/b.html", Title: "b", Created: day(3), Authors: []string{"jdoe", "asmith"}},
+		// updated after the newest post was created
+		{URL: "/a.html", Title: "a", Created: day(1), LastUpdated: day(5)},
+	}
+	cfg := Config{Config: config.Config{BaseURL: "https://example.com", FeedItems: 20}}
+	if err := writeFeed(outputDir, "site", "/", posts, cfg); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(outputDir, feedFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feed struct {
+		Channel struct {
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				Link     string   `xml:"link"`
+				Author   string   `xml:"author"`
+				Creators []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(b, &feed); err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Time(day(5)).Format(time.RFC1123Z); feed.Channel.LastBuildDate != want {
+		t.Errorf("lastBuildDate = %q, want %q", feed.Channel.LastBuildDate, want)
+	}
+	if len(feed.Channel.Items) != 2 {
+		t.Fatalf("items = %d, want 2", len(feed.Channel.Items))
+	}
+	first := feed.Channel.Items[0]
+	if first.Link != "https://example.com/b.html" {
+		t.Errorf("link = %q, want an absolute url", first.Link)
+	}
+	if first.Author != "" {
+		t.Errorf("author = %q, want none since rss authors are email addresses", first.Author)
+	}
+	if !slices.Equal(first.Creators, []string{"jdoe", "asmith"}) {
+		t.Errorf("creators = %q, want the names of the authors", first.Creators)
+	}
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jcocozza/jbf/internal/config"
+	"github.com/jcocozza/jbf/internal/dal"
+	"github.com/jcocozza/jbf/internal/metadata"
+	"github.com/jcocozza/jbf/internal/pandoc"
+	"github.com/jcocozza/jbf/internal/slug"
+	"github.com/jcocozza/jbf/internal/textutil"
+)
+
+// Funcs are the functions available in every layout
+func (s *Service) Funcs(cfg config.Config) template.FuncMap {
+	return template.FuncMap{
+		"date": func(d any) (string, error) {
+			return formatDate(cfg.DateFormat, d, cfg.Location())
+		},
+		"dateFormat": func(layout string, d any) (string, error) {
+			return formatDate(layout, d, cfg.Location())
+		},
+		"absURL": func(u string) string {
+			return absURL(cfg.BaseURL, u)
+		},
+		"relURL": func(u string) string {
+			return relURL(cfg.BaseURL, u)
+		},
+		"slugify":  slug.Make,
+		"truncate": truncate,
+		"plainify": func(s any) string {
+			return textutil.Plain(toString(s))
+		},
+		"markdownify": func(s any) (template.HTML, error) {
+			h, err := pandoc.PandocToHTML([]byte(toString(s)))
+			return template.HTML(h), err
+		},
+		"where":  where,
+		"sortBy": sortBy,
+		"first":  first,
+		"pages":  s.pages,
+		"asset": func(name string) string {
+			return s.assets.URL(name)
+		},
+	}
+}
+
+func toString(s any) string {
+	switch v := s.(type) {
+	case template.HTML:
+		return string(v)
+	case string:
+		return v
+	}
+	return fmt.Sprint(s)
+}
+
+// toTime reads the dates that show up in layouts: front matter dates, times and date strings. strings without a timezone are read in loc
+func toTime(d any, loc *time.Location) (time.Time, error) {
+	switch v := d.(type) {
+	case metadata.Date:
+		return time.Time(v), nil
+	case time.Time:
+		return v, nil
+	case string:
+		parsed, err := metadata.ParseDate(v, loc)
+		return time.Time(parsed), err
+	}
+	return time.Time{}, fmt.Errorf("%v is not a date", d)
+}
+
+// formatDate formats a date with a go time layout, e.g. {{ dateFormat "Jan 2, 2006" .Page.Created }}
+func formatDate(layout string, d any, loc *time.Location) (string, error) {
+	t, err := toTime(d, loc)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(layout), nil
+}
+
+func isAbs(u string) bool {
+	parsed, err := url.Parse(u)
+	return err == nil && parsed.IsAbs()
+}
+
+// relURL is the root relative url of u, under the path of the base url if it has one
+func relURL(baseURL string, u string) string {
+	if isAbs(u) {
+		return u
+	}
+	base := ""
+	if parsed, err := url.Parse(baseURL); err == nil {
+		base = strings.TrimSuffix(parsed.Path, "/")
+	}
+	return base + "/" + strings.TrimPrefix(u, "/")
+}
+
+// truncate shortens s to its first n words, e.g. {{ truncate 20 .Page.Summary }}
+func truncate(n int, s any) string {
+	return textutil.TruncateWords(toString(s), n)
+}
+
+// matches compares the front matter value of a page with the value given in a layout
+func matches(field any, value any) bool {
+	if list, ok := field.([]string); ok {
+		for _, v := range list {
+			if v == toString(value) {
+				return true
+			}
+		}
+		return false
+	}
+	return fmt.Sprint(field) == fmt.Sprint(value)
+}
+
+/*
+where keeps the pages whose front matter key equals value.
+list keys such as tags keep the pages that contain value.
+e.g. {{ range where .Related "series" "intro" }}
+*/
+func where(pages []metadata.Metadata, key string, value any) []metadata.Metadata {
+	kept := []metadata.Metadata{}
+	for _, m := range pages {
+		if matches(m.Field(key), value) {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// less orders two front matter values of the same key
+func less(a any, b any) bool {
+	if ad, ok := a.(metadata.Date); ok {
+		if bd, ok := b.(metadata.Date); ok {
+			return ad.Before(bd)
+		}
+	}
+	// yaml and toml numbers are ints while json numbers are float64s
+	if an, ok := toNumber(a); ok {
+		if bn, ok := toNumber(b); ok {
+			return an < bn
+		}
+	}
+	if a == nil {
+		return b != nil
+	}
+	if b == nil {
+		return false
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+/*
+sortBy sorts the pages by a front matter key, in ascending order unless "desc" is given.
+e.g. {{ range sortBy .Backlinks "title" }} or {{ range sortBy .Related "created" "desc" }}
+*/
+func sortBy(pages []metadata.Metadata, key string, order ...string) []metadata.Metadata {
+	sorted := make([]metadata.Metadata, len(pages))
+	copy(sorted, pages)
+	desc := len(order) > 0 && order[0] == "desc"
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if desc {
+			return less(sorted[j].Field(key), sorted[i].Field(key))
+		}
+		return less(sorted[i].Field(key), sorted[j].Field(key))
+	})
+	return sorted
+}
+
+// first is the first n pages, or all of them when there are fewer. a negative n is none
+func first(n int, pages []metadata.Metadata) []metadata.Metadata {
+	return pages[:max(0, min(n, len(pages)))]
+}
+
+/*
+pages queries the metadata store with key value pairs, newest first. e.g. {{ range pages "tag" "go" "limit" 5 }}
+tag and author filter by tag and author, sort and order ("asc" or "desc") sort by title, created, last_updated or a custom field,
+limit is the most pages to return and any other key filters on the custom front matter field.
+the home page is left out.
+*/
+func (s *Service) pages(args ...any) ([]metadata.Metadata, error) {
+	if len(args)%2 != 0 {
+		return nil, fmt.Errorf("pages takes key value pairs, got %d arguments", len(args))
+	}
+	q := dal.Query{Params: map[string]any{}}
+	for i := 0; i < len(args); i += 2 {
+		key, ok := args[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("pages: key %v is not a string", args[i])
+		}
+		value := args[i+1]
+		switch key {
+		case "tag":
+			q.Tag = toString(value)
+		case "author":
+			q.Author = toString(value)
+		case "sort":
+			q.SortBy = toString(value)
+		case "order":
+			q.Asc = toString(value) == "asc"
+		case "limit":
+			n, ok := value.(int)
+			if !ok {
+				return nil, fmt.Errorf("pages: limit %v is not a number", value)
+			}
+			q.Limit = n
+		default:
+			q.Params[key] = value
+		}
+	}
+	limit := q.Limit
+	if limit > 0 {
+		// room for the home page, which is dropped below
+		q.Limit++
+	}
+	all, err := s.dal.QueryMetadata(q)
+	if err != nil {
+		return nil, err
+	}
+	posts := feedPosts(all)
+	if limit > 0 {
+		posts = first(limit, posts)
+	}
+	return posts, nil
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jcocozza/jbf/internal/metadata"
+)
+
+func testPages() []metadata.Metadata {
+	return []metadata.Metadata{
+		{Filepath: "a.md", URL: "/a.html", Title: "b title", Created: day(1), Tags: []string{"go", "web"}, Series: "intro", SeriesOrder: 2, Params: map[string]any{"weight": 10}},
+		{Filepath: "b.md", URL: "/b.html", Title: "a title", Created: day(3), Tags: []string{"go"}, Params: map[string]any{"weight": 9.5}},
+		{Filepath: "c.md", URL: "/c.html", Title: "c title", Created: day(2), Series: "intro", SeriesOrder: 1, Params: map[string]any{"weight": 2, "featured": true}},
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name  string
+		field any
+		value any
+		want  bool
+	}{
+		{"string", "intro", "intro", true},
+		{"other string", "intro", "outro", false},
+		{"int and string", 2, "2", true},
+		{"bool", true, true, true},
+		{"bool and string", true, "true", true},
+		{"in list", []string{"go", "web"}, "web", true},
+		{"not in list", []string{"go", "web"}, "rust", false},
+		{"empty list", []string{}, "go", false},
+		{"nil", nil, "go", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matches(tt.field, tt.value); got != tt.want {
+				t.Errorf("matches(%v, %v) = %v, want %v", tt.field, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWhere(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value any
+		want  []string
+	}{
+		{"field", "series", "intro", []string{"a.md", "c.md"}},
+		{"list field", "tags", "go", []string{"a.md", "b.md"}},
+		{"custom field", "featured", true, []string{"c.md"}},
+		{"number", "series_order", 1, []string{"c.md"}},
+		{"no match", "series", "outro", []string{}},
+		{"missing key", "missing", "x", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := files(where(testPages(), tt.key, tt.value)); !slices.Equal(got, tt.want) {
+				t.Errorf("where(%s, %v) = %v, want %v", tt.key, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		order []string
+		want  []string
+	}{
+		{"title", "title", nil, []string{"b.md", "a.md", "c.md"}},
+		{"date", "created", nil, []string{"a.md", "c.md", "b.md"}},
+		{"date desc", "created", []string{"desc"}, []string{"b.md", "c.md", "a.md"}},
+		// 10 sorts after 9.5 even though one is an int and the other a float
+		{"numbers", "weight", nil, []string{"c.md", "b.md", "a.md"}},
+		{"numbers desc", "weight", []string{"desc"}, []string{"a.md", "b.md", "c.md"}},
+		// missing values sort first and the order of equal values is kept
+		{"missing", "featured", nil, []string{"a.md", "b.md", "c.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages := testPages()
+			if got := files(sortBy(pages, tt.key, tt.order...)); !slices.Equal(got, tt.want) {
+				t.Errorf("sortBy(%s, %v) = %v, want %v", tt.key, tt.order, got, tt.want)
+			}
+			if got := files(pages); !slices.Equal(got, []string{"a.md", "b.md", "c.md"}) {
+				t.Errorf("sortBy changed the pages it was given to %v", got)
+			}
+		})
+	}
+}
+
+func TestFirst(t *testing.T) {
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{-1, []string{}},
+		{0, []string{}},
+		{2, []string{"a.md", "b.md"}},
+		{3, []string{"a.md", "b.md", "c.md"}},
+		{10, []string{"a.md", "b.md", "c.md"}},
+	}
+	for _, tt := range tests {
+		if got := files(first(tt.n, testPages())); !slices.Equal(got, tt.want) {
+			t.Errorf("first(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+	if got := first(2, nil); len(got) != 0 {
+		t.Errorf("first(2, nil) = %v, want none", got)
+	}
+}
+
+func TestPages(t *testing.T) {
+	home := metadata.Metadata{Filepath: "index.md", URL: "/", Title: "home", Created: day(4)}
+	s := testService(t, append(testPages(), home)...)
+	tests := []struct {
+		name    string
+		args    []any
+		want    []string
+		wantErr bool
+	}{
+		{"everything but the home page", nil, []string{"b.md", "c.md", "a.md"}, false},
+		{"tag", []any{"tag", "web"}, []string{"a.md"}, false},
+		{"series in order", []any{"series", "intro", "sort", "series_order", "order", "asc"}, []string{"c.md", "a.md"}, false},
+		{"custom field", []any{"featured", true}, []string{"c.md"}, false},
+		// the home page is the newest, so the limit has to make room for it
+		{"limit", []any{"limit", 2}, []string{"b.md", "c.md"}, false},
+		{"odd arguments", []any{"tag"}, nil, true},
+		{"key not a string", []any{1, "x"}, nil, true},
+		{"limit not a number", []any{"limit", "2"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.pages(tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pages(%v) error = %v, want error %v", tt.args, err, tt.wantErr)
+			}
+			if !tt.wantErr && !slices.Equal(files(got), tt.want) {
+				t.Errorf("pages(%v) = %v, want %v", tt.args, files(got), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jcocozza/jbf/internal/git"
+	"github.com/jcocozza/jbf/internal/metadata"
+)
+
+/*
+applyGitHistory fills in the created, last_updated and author fields that the front matter leaves out
+with the first commit, last commit and last commit author of the file.
+fields that are set are kept, but a warning is printed when they don't match the history (compared as days in loc).
+*/
+func applyGitHistory(m *metadata.Metadata, loc *time.Location) error {
+	h, ok, err := git.FileHistory(m.Filepath)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// not committed yet
+		return nil
+	}
+	created, lastUpdated := metadata.Date(h.Created), metadata.Date(h.LastUpdated)
+	if m.Created.IsZero() {
+		m.Created = created
+	} else if !m.Created.In(loc).Equal(created.In(loc)) {
+		fmt.Fprintf(os.Stderr, "warning: %s: created (%s) does not match the first commit (%s)\n", m.Filepath, m.Created, created.In(loc))
+	}
+	if m.LastUpdated.IsZero() {
+		m.LastUpdated = lastUpdated
+	} else if !m.LastUpdated.In(loc).Equal(lastUpdated.In(loc)) {
+		fmt.Fprintf(os.Stderr, "warning: %s: last_updated (%s) does not match the last commit (%s)\n", m.Filepath, m.LastUpdated, lastUpdated.In(loc))
+	}
+	if m.Author == "" {
+		m.Author = h.LastAuthor
+		m.SetAuthors()
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"encoding/json"
+	"html/template"
+	"time"
+)
+
+// Head is the metadata of a page for search engines and link previews, e.g. {{ with .Head }}<title>{{ .Title }}</title>{{ end }}
+type Head struct {
+	// the title of the page followed by the site name
+	Title string
+	// the title of the page on its own, for link previews
+	PageTitle   string
+	SiteName    string
+	Description string
+	// the absolute url of the page
+	Canonical string
+	// the absolute url of the preview image, if any
+	Image string
+	// article for content and website for everything else
+	Type string
+	// the created and last updated dates of articles in RFC3339
+	Published string
+	Modified  string
+	Tags      []string
+	// summary_large_image when there is an image and summary otherwise
+	TwitterCard string
+	// the schema.org BlogPosting of articles
+	JSONLD template.JS
+}
+
+// isArticle reports whether the page is a content file, not counting the home page
+func (d LayoutData) isArticle() bool {
+	return d.Page.Filepath != "" && d.Page.URL != "/"
+}
+
+// Head builds the head metadata of the page from its front matter and the site config
+func (d LayoutData) Head() Head {
+	var site Site
+	if d.Site != nil {
+		site = *d.Site
+	}
+	h := Head{
+		Title:       d.Page.Title,
+		PageTitle:   d.Page.Title,
+		SiteName:    site.Name,
+		Description: d.Page.Description,
+		Canonical:   absURL(site.BaseURL, d.Page.URL),
+		Type:        "website",
+		TwitterCard: "summary",
+	}
+	if d.Paginator != nil {
+		h.Canonical = absURL(site.BaseURL, d.Paginator.URL)
+	}
+	if h.Title == "" || d.Page.URL == "/" {
+		h.Title = site.Name
+	} else if site.Name != "" {
+		h.Title += " | " + site.Name
+	}
+	if h.PageTitle == "" {
+		h.PageTitle = site.Name
+	}
+	if h.Description == "" {
+		h.Description = d.Page.Summary
+	}
+	if d.Page.Image != "" {
+		h.Image = absURL(site.BaseURL, d.Page.Image)
+		h.TwitterCard = "summary_large_image"
+	}
+	if !d.isArticle() {
+		return h
+	}
+	h.Type = "article"
+	h.Tags = d.Page.Tags
+	if !d.Page.Created.IsZero() {
+		h.Published = time.Time(d.Page.Created).Format(time.RFC3339)
+	}
+	if !d.Page.LastUpdated.IsZero() {
+		h.Modified = time.Time(d.Page.LastUpdated).Format(time.RFC3339)
+	}
+	h.JSONLD = blogPosting(h, d, site)
+	return h
+}
+
+type person struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type blogPostingLD struct {
+	Context          string   `json:"@context"`
+	Type             string   `json:"@type"`
+	Headline         string   `json:"headline"`
+	Description      string   `json:"description,omitempty"`
+	URL              string   `json:"url"`
+	MainEntityOfPage string   `json:"mainEntityOfPage"`
+	Image            string   `json:"image,omitempty"`
+	DatePublished    string   `json:"datePublished,omitempty"`
+	DateModified     string   `json:"dateModified,omitempty"`
+	Keywords         []string `json:"keywords,omitempty"`
+	Author           []person `json:"author,omitempty"`
+	Publisher        *person  `json:"publisher,omitempty"`
+}
+
+// blogPosting is the schema.org BlogPosting of the page as json.
+// json.Marshal escapes <, > and &, so it is safe to put in a script tag.
+func blogPosting(h Head, d LayoutData, site Site) template.JS {
+	ld := blogPostingLD{
+		Context:          "https://schema.org",
+		Type:             "BlogPosting",
+		Headline:         d.Page.Title,
+		Description:      h.Description,
+		URL:              h.Canonical,
+		MainEntityOfPage: h.Canonical,
+		Image:            h.Image,
+		DatePublished:    h.Published,
+		DateModified:     h.Modified,
+		Keywords:         h.Tags,
+	}
+	for _, a := range d.Authors {
+		ld.Author = append(ld.Author, person{Type: "Person", Name: a.Name, URL: absURL(site.BaseURL, a.URL())})
+	}
+	if site.Name != "" {
+		ld.Publisher = &person{Type: "Organization", Name: site.Name}
+	}
+	b, err := json.Marshal(ld)
+	if err != nil {
+		return ""
+	}
+	return template.JS(b)
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/jcocozza/jbf/internal/theme"
+)
+
+// sectionLayouts is the name in the directory of the content and each of its parents, nearest first.
+// e.g. blog/2024/single, blog/single
+func sectionLayouts(dir string, name string) []string {
+	names := []string{}
+	for dir != "." && dir != "/" && dir != "" {
+		names = append(names, dir+"/"+name)
+		dir = path.Dir(dir)
+	}
+	return names
+}
+
+/*
+pageLayout is the layout a content page is rendered with. the first one the theme has of
+  - the layout from the front matter in the section directory of the page, then in the parent directories
+  - the layout from the front matter
+  - single in the section directory of the page, then in the parent directories
+  - single
+
+e.g. a page in blog/ with layout: slides looks for blog/slides, slides, blog/single and single
+*/
+func pageLayout(p page, cfg Config) string {
+	if p.md.Layout != "" {
+		if name, ok := firstLayout(cfg.Theme, append(sectionLayouts(p.dir, p.md.Layout), p.md.Layout)); ok {
+			return name
+		}
+		fmt.Fprintf(os.Stderr, "warning: %s: unknown layout %s, using the default\n", p.inputPath, p.md.Layout)
+	}
+	if name, ok := firstLayout(cfg.Theme, sectionLayouts(p.dir, theme.Single)); ok {
+		return name
+	}
+	return theme.Single
+}
+
+// firstLayout is the first of names that the theme has
+func firstLayout(t *theme.Theme, names []string) (string, bool) {
+	for _, name := range names {
+		if t.Exists(name) {
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jcocozza/jbf/internal/metadata"
+	"github.com/jcocozza/jbf/internal/paginate"
+)
+
+// listHTML renders posts as a list of links with their created date, reading time and summary
+func listHTML(posts []metadata.Metadata, dateFormat string) string {
+	var b strings.Builder
+	b.WriteString("<ul class=\"list\">\n")
+	for _, m := range posts {
+		b.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a> <time>%s</time>", m.URL, template.HTMLEscapeString(m.Title), m.Created.Format(dateFormat)))
+		if m.ReadingTime > 0 {
+			b.WriteString(fmt.Sprintf(" <span class=\"reading-time\">%d min read</span>", m.ReadingTime))
+		}
+		if m.Summary != "" {
+			b.WriteString(fmt.Sprintf("<p class=\"summary\">%s</p>", template.HTMLEscapeString(m.Summary)))
+		}
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</ul>\n")
+	return b.String()
+}
+
+// outputFile is the path in the output dir of the file name under the escaped url u
+func outputFile(outputDir string, u string, name string) string {
+	if unescaped, err := url.PathUnescape(u); err == nil {
+		u = unescaped
+	}
+	return filepath.Join(outputDir, filepath.FromSlash(u), name)
+}
+
+/*
+writeList writes posts as a paginated list page with the named layout at the url of pageMd (e.g. /tags/go/)
+and the following pages at /tags/go/page/2/ and so on.
+intro is only shown on the first page.
+*/
+func (s *Service) writeList(outputDir string, layout string, pageMd metadata.Metadata, intro string, posts []metadata.Metadata, cfg Config) error {
+	total := paginate.Pages(len(posts), cfg.Paginate)
+	for n := 1; n <= total; n++ {
+		pager := paginate.New(pageMd.URL, len(posts), cfg.Paginate, n)
+		content := fmt.Sprintf("<h1>%s</h1>\n", template.HTMLEscapeString(pageMd.Title))
+		if n == 1 {
+			content += intro
+		}
+		content += listHTML(paginate.Slice(posts, pager), cfg.DateFormat)
+		data := LayoutData{
+			Content:   template.HTML(content),
+			Name:      cfg.Name,
+			Page:      pageMd,
+			Paginator: &pager,
+		}
+		p := outputFile(outputDir, pager.URL, "index.html")
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		if err := writePage(p, layout, data, cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
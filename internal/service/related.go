@@ -0,0 +1,58 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jcocozza/jbf/internal/metadata"
+)
+
+// how many days apart two posts are when the recency weight is halved
+const relatedHalfLife = 30.0
+
+/*
+related returns the n posts most related to m.
+each shared tag is worth 1 and posts created close to m are worth up to 1 more,
+so more shared tags always wins and recency breaks the ties.
+posts without a shared tag are never related.
+*/
+func related(m metadata.Metadata, all []metadata.Metadata, n int) []metadata.Metadata {
+	if n <= 0 || len(m.Tags) == 0 {
+		return nil
+	}
+	tags := map[string]bool{}
+	for _, tag := range m.Tags {
+		tags[tag] = true
+	}
+	type scored struct {
+		m     metadata.Metadata
+		score float64
+	}
+	candidates := []scored{}
+	for _, other := range all {
+		if other.Filepath == m.Filepath {
+			continue
+		}
+		shared := 0
+		for _, tag := range other.Tags {
+			if tags[tag] {
+				shared++
+			}
+		}
+		if shared == 0 {
+			continue
+		}
+		days := math.Abs(time.Time(m.Created).Sub(time.Time(other.Created)).Hours() / 24)
+		recency := 1 / (1 + days/relatedHalfLife)
+		candidates = append(candidates, scored{m: other, score: float64(shared) + recency})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	rel := []metadata.Metadata{}
+	for i := 0; i < len(candidates) && i < n; i++ {
+		rel = append(rel, candidates[i].m)
+	}
+	return rel
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jcocozza/jbf/internal/metadata"
+)
+
+func TestRelated(t *testing.T) {
+	post := metadata.Metadata{Filepath: "post.md", Tags: []string{"go", "web", "sql"}, Created: day(15)}
+	tests := []struct {
+		name string
+		m    metadata.Metadata
+		all  []metadata.Metadata
+		n    int
+		want []string
+	}{
+		{
+			name: "more shared tags wins over recency",
+			m:    post,
+			all: []metadata.Metadata{
+				{Filepath: "one-tag-same-day.md", Tags: []string{"go"}, Created: day(15)},
+				{Filepath: "two-tags-old.md", Tags: []string{"go", "web"}, Created: metadata.Date{}},
+				{Filepath: "three-tags.md", Tags: []string{"sql", "web", "go"}, Created: day(1)},
+			},
+			n:    5,
+			want: []string{"three-tags.md", "two-tags-old.md", "one-tag-same-day.md"},
+		},
+		{
+			name: "closer posts win ties either side of the post",
+			m:    post,
+			all: []metadata.Metadata{
+				{Filepath: "far-before.md", Tags: []string{"go"}, Created: day(1)},
+				{Filepath: "after.md", Tags: []string{"web"}, Created: day(18)},
+				{Filepath: "before.md", Tags: []string{"sql"}, Created: day(10)},
+				{Filepath: "far-after.md", Tags: []string{"go"}, Created: day(31)},
+			},
+			n:    5,
+			want: []string{"after.md", "before.md", "far-before.md", "far-after.md"},
+		},
+		{
+			name: "equal scores keep their order",
+			m:    post,
+			all: []metadata.Metadata{
+				{Filepath: "b.md", Tags: []string{"go"}, Created: day(10)},
+				{Filepath: "a.md", Tags: []string{"web"}, Created: day(20)},
+			},
+			n:    5,
+			want: []string{"b.md", "a.md"},
+		},
+		{
+			name: "the post itself and posts without a shared tag are left out",
+			m:    post,
+			all: []metadata.Metadata{
+				post,
+				{Filepath: "untagged.md", Created: day(15)},
+				{Filepath: "other-tags.md", Tags: []string{"rust"}, Created: day(15)},
+				{Filepath: "shared.md", Tags: []string{"go"}, Created: day(2)},
+			},
+			n:    5,
+			want: []string{"shared.md"},
+		},
+		{
+			name: "at most n",
+			m:    post,
+			all: []metadata.Metadata{
+				{Filepath: "a.md", Tags: []string{"go"}, Created: day(14)},
+				{Filepath: "b.md", Tags: []string{"go", "web"}, Created: day(1)},
+				{Filepath: "c.md", Tags: []string{"go"}, Created: day(15)},
+			},
+			n:    2,
+			want: []string{"b.md", "c.md"},
+		},
+		{
+			name: "disabled",
+			m:    post,
+			all:  []metadata.Metadata{{Filepath: "a.md", Tags: []string{"go"}, Created: day(15)}},
+			n:    0,
+			want: []string{},
+		},
+		{
+			name: "post without tags",
+			m:    metadata.Metadata{Filepath: "post.md", Created: day(15)},
+			all:  []metadata.Metadata{{Filepath: "a.md", Tags: []string{"go"}, Created: day(15)}},
+			n:    5,
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := files(related(tt.m, tt.all, tt.n))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("related() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
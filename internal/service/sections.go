@@ -0,0 +1,88 @@
+package service
+
+import (
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jcocozza/jbf/internal/metadata"
+	"github.com/jcocozza/jbf/internal/pandoc"
+	"github.com/jcocozza/jbf/internal/theme"
+)
+
+// sectionIntroFile is an optional file in a section directory with the title and intro of the section list page
+const sectionIntroFile = "_index.md"
+
+// section is a subdirectory of the content directory
+type section struct {
+	// slash separated and relative to the content directory
+	dir string
+	// the directory has its own index file, so no list page is generated
+	hasIndex bool
+	// the _index.md of the section, if any
+	introPath string
+}
+
+// url is the escaped url of the section, like the urls of its content
+func (sec section) url() string {
+	return (&url.URL{Path: "/" + sec.dir + "/"}).String()
+}
+
+// isIndexFile reports whether the content file is served as the index of its directory
+func isIndexFile(path string) bool {
+	name := filepath.Base(path)
+	return strings.TrimSuffix(name, filepath.Ext(name)) == "index"
+}
+
+// sectionPosts returns the content under the section directory, ordered by sortBy (date or title)
+func sectionPosts(sec section, all []metadata.Metadata, sortBy string) []metadata.Metadata {
+	posts := []metadata.Metadata{}
+	for _, m := range all {
+		if strings.HasPrefix(m.URL, sec.url()) && m.URL != sec.url() {
+			posts = append(posts, m)
+		}
+	}
+	if sortBy == "title" {
+		sort.SliceStable(posts, func(i, j int) bool {
+			return strings.ToLower(posts[i].Title) < strings.ToLower(posts[j].Title)
+		})
+	}
+	return posts
+}
+
+// writeSectionPages creates a paginated list page for every section without an index file
+func (s *Service) writeSectionPages(outputDir string, sections []section, all []metadata.Metadata, cfg Config) error {
+	for _, sec := range sections {
+		if sec.hasIndex {
+			continue
+		}
+		pageMd := metadata.Metadata{Title: path.Base(sec.dir), URL: sec.url()}
+		intro := ""
+		sortBy := cfg.Sections.Sort
+		if sec.introPath != "" {
+			md, body, _, err := metadata.SplitFile(sec.introPath, cfg.Location())
+			if err != nil {
+				return err
+			}
+			intro, err = pandoc.PandocToHTML(body)
+			if err != nil {
+				return err
+			}
+			if sortParam, ok := md.Param("sort").(string); ok {
+				sortBy = sortParam
+			}
+			if md.Title == "" {
+				md.Title = pageMd.Title
+			}
+			md.URL = pageMd.URL
+			pageMd = md
+		}
+		posts := sectionPosts(sec, all, sortBy)
+		if err := s.writeList(outputDir, theme.List, pageMd, intro, posts, cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
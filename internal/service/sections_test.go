@@ -0,0 +1,85 @@
+package service
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jcocozza/jbf/internal/metadata"
+)
+
+func TestContentURL(t *testing.T) {
+	contentDir := filepath.FromSlash("/site/content")
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"index.md", "/"},
+		{"about.md", "/about.html"},
+		{"blog/index.md", "/blog/"},
+		{"blog/first-post.md", "/blog/first-post.html"},
+		{"blog/2024/index.md", "/blog/2024/"},
+		{"blog/myindex.md", "/blog/myindex.html"},
+		{"my posts/first post.md", "/my%20posts/first%20post.html"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := contentURL(filepath.Join(contentDir, filepath.FromSlash(tt.path)), contentDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("contentURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSectionURL(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{"blog", "/blog/"},
+		{"blog/2024", "/blog/2024/"},
+		{"my posts", "/my%20posts/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			if got := (section{dir: tt.dir}).url(); got != tt.want {
+				t.Errorf("url() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSectionPosts(t *testing.T) {
+	all := []metadata.Metadata{
+		{Filepath: "blog/b.md", URL: "/blog/b.html", Title: "beta"},
+		{Filepath: "blog/index.md", URL: "/blog/", Title: "Blog"},
+		{Filepath: "blog/2024/a.md", URL: "/blog/2024/a.html", Title: "Alpha"},
+		{Filepath: "blog-old/c.md", URL: "/blog-old/c.html", Title: "gamma"},
+		{Filepath: "blog.md", URL: "/blog.html", Title: "blog"},
+		{Filepath: "my posts/d.md", URL: "/my%20posts/d.html", Title: "delta"},
+	}
+	tests := []struct {
+		name   string
+		dir    string
+		sortBy string
+		want   []string
+	}{
+		{"nested posts but not the index or similar names", "blog", "date", []string{"blog/b.md", "blog/2024/a.md"}},
+		{"by title", "blog", "title", []string{"blog/2024/a.md", "blog/b.md"}},
+		{"subsection", "blog/2024", "date", []string{"blog/2024/a.md"}},
+		{"escaped directory", "my posts", "date", []string{"my posts/d.md"}},
+		{"empty", "drafts", "date", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := files(sectionPosts(section{dir: tt.dir}, all, tt.sortBy))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("sectionPosts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jcocozza/jbf/internal/metadata"
+	"github.com/jcocozza/jbf/internal/slug"
+	"github.com/jcocozza/jbf/internal/theme"
+)
+
+// Series is a named, ordered collection of posts
+type Series struct {
+	Name  string
+	URL   string
+	Parts []metadata.Metadata
+	// the 1 based position of the current page in Parts. 0 on the series index page
+	Position int
+}
+
+func seriesURL(name string) string {
+	return "/series/" + slug.Path(name) + "/"
+}
+
+// collectSeries groups content by series. parts are ordered by series_order and then created.
+// series whose names have the same slug, e.g. "part one" and "Part One", are an error since they would share a page.
+func collectSeries(all []metadata.Metadata) ([]*Series, error) {
+	byName := map[string]*Series{}
+	series := []*Series{}
+	for _, m := range all {
+		if m.Series == "" {
+			continue
+		}
+		s, ok := byName[m.Series]
+		if !ok {
+			s = &Series{Name: m.Series, URL: seriesURL(m.Series)}
+			byName[m.Series] = s
+			series = append(series, s)
+		}
+		s.Parts = append(s.Parts, m)
+	}
+	for _, s := range series {
+		sort.SliceStable(s.Parts, func(i, j int) bool {
+			a, b := s.Parts[i], s.Parts[j]
+			if a.SeriesOrder != b.SeriesOrder {
+				return a.SeriesOrder < b.SeriesOrder
+			}
+			return a.Created.Before(b.Created)
+		})
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Name < series[j].Name })
+	byURL := map[string]string{}
+	for _, s := range series {
+		if other, ok := byURL[s.URL]; ok {
+			return nil, fmt.Errorf("series %q and %q would both be written to %s, rename one of them", other, s.Name, s.URL)
+		}
+		byURL[s.URL] = s.Name
+	}
+	return series, nil
+}
+
+/*
+navigation returns the series m is part of along with the previous and next pages.
+in a series these are the neighbouring parts, otherwise the neighbouring posts by date.
+all must be ordered newest first, like ReadAllMetadata.
+*/
+func navigation(m metadata.Metadata, all []metadata.Metadata, series []*Series) (*Series, *metadata.Metadata, *metadata.Metadata) {
+	if m.Series != "" {
+		for _, s := range series {
+			if s.Name != m.Series {
+				continue
+			}
+			for i, part := range s.Parts {
+				if part.Filepath != m.Filepath {
+					continue
+				}
+				current := *s
+				current.Position = i + 1
+				var prev, next *metadata.Metadata
+				if i > 0 {
+					prev = &s.Parts[i-1]
+				}
+				if i < len(s.Parts)-1 {
+					next = &s.Parts[i+1]
+				}
+				return &current, prev, next
+			}
+		}
+	}
+	// the home page is not part of the chronological order
+	posts := []metadata.Metadata{}
+	for _, other := range all {
+		if other.URL != "/" {
+			posts = append(posts, other)
+		}
+	}
+	for i, other := range posts {
+		if other.Filepath != m.Filepath {
+			continue
+		}
+		var prev, next *metadata.Metadata
+		if i < len(posts)-1 {
+			prev = &posts[i+1]
+		}
+		if i > 0 {
+			next = &posts[i-1]
+		}
+		return nil, prev, next
+	}
+	return nil, nil, nil
+}
+
+// writeSeriesPages creates an index page for every series at /series/<name>/
+func (s *Service) writeSeriesPages(outputDir string, series []*Series, cfg Config) error {
+	for _, ser := range series {
+		var b strings.Builder
+		b.WriteString(fmt.Sprintf("<h1>%s</h1>\n<ol>\n", template.HTMLEscapeString(ser.Name)))
+		for _, part := range ser.Parts {
+			b.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a></li>\n", part.URL, template.HTMLEscapeString(part.Title)))
+		}
+		b.WriteString("</ol>\n")
+		data := LayoutData{
+			Content: template.HTML(b.String()),
+			Name:    cfg.Name,
+			Page:    metadata.Metadata{Title: ser.Name, URL: ser.URL},
+			Series:  ser,
+		}
+		path := filepath.Join(outputDir, filepath.FromSlash(ser.URL), "index.html")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := writePage(path, theme.List, data, cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,24 +1,123 @@
 package service
 
 import (
+	"github.com/jcocozza/jbf/internal/assets"
+	"github.com/jcocozza/jbf/internal/author"
+	"github.com/jcocozza/jbf/internal/cards"
+	"github.com/jcocozza/jbf/internal/check"
+	"github.com/jcocozza/jbf/internal/config"
 	"github.com/jcocozza/jbf/internal/dal"
+	"github.com/jcocozza/jbf/internal/fsutil"
+	"github.com/jcocozza/jbf/internal/git"
+	"github.com/jcocozza/jbf/internal/images"
+	"github.com/jcocozza/jbf/internal/menu"
 	"github.com/jcocozza/jbf/internal/metadata"
 	"github.com/jcocozza/jbf/internal/pandoc"
-	"github.com/jcocozza/jbf/internal/styles"
+	"github.com/jcocozza/jbf/internal/paginate"
+	"github.com/jcocozza/jbf/internal/shortcode"
+	"github.com/jcocozza/jbf/internal/textutil"
+	"github.com/jcocozza/jbf/internal/theme"
+	"errors"
 	"fmt"
 	"html/template"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
 
 type Config struct {
-	Name   string
-	Layout *template.Template
+	config.Config
+	Theme *theme.Theme
+	// the author profiles that front matter authors resolve to
+	Authors author.Registry
+	// the shortcodes that are expanded in the content
+	Shortcodes *shortcode.Set
+	// the menus from the config with the content that is in them
+	menus menu.Menus
+	// makes the variants of images in content when images are enabled
+	images *images.Processor
+	// draws the link preview images of posts when cards are enabled
+	cards *cards.Generator
+}
+
+// Site is the site wide data available to every layout
+type Site struct {
+	Name    string
+	BaseURL string
+	// the menus with the entries of the page being rendered marked active, e.g. .Site.Menus.main
+	Menus menu.Menus
+	// the css variables set in the style config, e.g. --accent: #2563eb;
+	Style template.CSS
+}
+
+func newSite(cfg config.Config, menus menu.Menus, pageURL string) *Site {
+	return &Site{
+		Name:    cfg.Name,
+		BaseURL: cfg.BaseURL,
+		Menus:   menus.Active(pageURL),
+		Style:   template.CSS(cfg.Style.Vars()),
+	}
+}
+
+// Site builds the site data for the page at pageURL from the indexed content
+func (s *Service) Site(cfg config.Config, pageURL string) (*Site, error) {
+	all, err := s.dal.ReadAllMetadata()
+	if err != nil {
+		return nil, err
+	}
+	return newSite(cfg, menu.Build(cfg.Menus, all), pageURL), nil
+}
+
+// Routes are served by jbf serve without a file in the output directory
+var Routes = []string{"/all"}
+
+// LayoutData is what the layout is executed with for every page
+type LayoutData struct {
+	Content template.HTML
+	Name    string
+	// site wide data such as the menus. set when the page is written
+	Site *Site
+	// the metadata of the page. custom front matter fields are in .Page.Params
+	Page metadata.Metadata
+	// the profiles of the authors of the page
+	Authors []author.Author
+	// the pages with a wiki link to this page
+	Backlinks []metadata.Metadata
+	// the pages that share the most tags with this page
+	Related []metadata.Metadata
+	// the series the page is part of, if any
+	Series *Series
+	// the previous and next part of the series, or the previous and next post by date
+	Prev *metadata.Metadata
+	Next *metadata.Metadata
+	// set on list pages that are split over several pages
+	Paginator *paginate.Paginator
+}
+
+// page is a content file being compiled
+type page struct {
+	inputPath  string
+	outputPath string
+	// the directory of the content file relative to the content dir, "." at the root
+	dir     string
+	md      metadata.Metadata
+	body    []byte
+	// the line of the content file that body starts on
+	bodyLine int
+	// the rendered content, set by convertContent
+	html    string
+	related []metadata.Metadata
+	series  *Series
+	prev    *metadata.Metadata
+	next    *metadata.Metadata
 }
 
 type Service struct {
 	dal dal.Repository
+	// the urls of the static files written by the last compile
+	assets assets.Manifest
 }
 
 func NewService(d dal.Repository) *Service {
@@ -35,10 +134,6 @@ func (s *Service) NewFile(contentDir string, fname string) error {
 }
 
 func (s *Service) processTag(m metadata.Metadata, tagName string) error {
-	exists := s.dal.ReadTagExists(tagName)
-	if exists {
-		return nil
-	}
 	err := s.dal.CreateTag(m.ID, tagName)
 	if err != nil {
 		return err
@@ -56,11 +151,25 @@ func (s *Service) processMetadataTags(m metadata.Metadata) error {
 	return nil
 }
 
+func (s *Service) processMetadataAuthors(m metadata.Metadata) error {
+	for i, a := range m.Authors {
+		err := s.dal.CreateAuthor(m.ID, a, i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *Service) updateMetadata(m metadata.Metadata) error {
 	err := s.processMetadataTags(m)
 	if err != nil {
 		return err
 	}
+	err = s.processMetadataAuthors(m)
+	if err != nil {
+		return err
+	}
 	return s.dal.UpdateMetadata(m)
 }
 
@@ -70,62 +179,137 @@ func (s *Service) createMetadata(m *metadata.Metadata) error {
 		return err
 	}
 	m.ID = id
-	return s.processMetadataTags(*m)
+	err = s.processMetadataTags(*m)
+	if err != nil {
+		return err
+	}
+	return s.processMetadataAuthors(*m)
 }
 
 func (s *Service) ListContentByDate() ([]metadata.Metadata, error) {
 	return s.dal.ReadAllMetadata()
 }
 
-func (s *Service) updateDB(filepath string) error {
-	md, err := metadata.ExtractFromFile(filepath)
+// ListContent returns the content matching the query
+func (s *Service) ListContent(q dal.Query) ([]metadata.Metadata, error) {
+	return s.dal.QueryMetadata(q)
+}
+
+// updateDB writes the metadata of the file to the db and returns it along with the body of the file and the line the body starts on
+func (s *Service) updateDB(path string, contentDir string, cfg Config) (metadata.Metadata, []byte, int, error) {
+	md, body, bodyLine, err := metadata.SplitFile(path, cfg.Location())
 	if err != nil {
-		return err
+		return metadata.Metadata{}, nil, 0, err
+	}
+	if cfg.GitDates {
+		err = applyGitHistory(&md, cfg.Location())
+		if err != nil {
+			return metadata.Metadata{}, nil, 0, err
+		}
+	}
+	md.URL, err = contentURL(path, contentDir)
+	if err != nil {
+		return metadata.Metadata{}, nil, 0, err
 	}
-	fmt.Printf("got metadata for file %s:\n%s\n", filepath, md.String())
-	exists := s.dal.ReadMetadataExists(filepath)
+	fmt.Printf("got metadata for file %s:\n%s\n", path, md.String())
+	exists := s.dal.ReadMetadataExists(path)
 	if exists {
-		return s.updateMetadata(md)
+		existing, err := s.dal.ReadMetadata(path)
+		if err != nil {
+			return metadata.Metadata{}, nil, 0, err
+		}
+		md.ID = existing.ID
+		return md, body, bodyLine, s.updateMetadata(md)
 	}
-	return s.createMetadata(&md)
+	return md, body, bodyLine, s.createMetadata(&md)
 }
 
-func (s *Service) processContentFile(inputPath string, outputPath string, cfg Config) error {
-	err := s.updateDB(inputPath)
+// convertContent renders the markdown of the page to html and stores what is computed from it: the summary, word count and reading time
+func (s *Service) convertContent(p *page, cfg Config) error {
+	body, err := cfg.Shortcodes.Expand(p.body, p.md)
+	var serr *shortcode.Error
+	if errors.As(err, &serr) {
+		// the line is in the body, which comes after the front matter
+		serr.Line += p.bodyLine - 1
+	}
 	if err != nil {
-		return err
+		return fmt.Errorf("%s: %w", p.inputPath, err)
 	}
-	base, err := pandoc.PandocToHTML(inputPath)
+	base, err := pandoc.PandocToHTML(body)
 	if err != nil {
 		return err
 	}
-	var htmlContentBuilder strings.Builder
-	var data = struct {
-		Content template.HTML
-		Name    string
-	}{
-		Content: template.HTML(base),
-		Name:    cfg.Name,
+	if p.md.Summary == "" {
+		p.md.Summary = summarize(base, cfg.SummaryWords)
 	}
-	err = cfg.Layout.Execute(&htmlContentBuilder, data)
+	if cfg.images != nil {
+		base, err = cfg.images.Rewrite(base, p.md.URL)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.inputPath, err)
+		}
+	}
+	p.md.WordCount = textutil.WordCount(textutil.Plain(base))
+	p.md.ReadingTime = readingTime(p.md.WordCount, cfg.WordsPerMinute)
+	p.html = base
+	return s.dal.UpdateMetadata(p.md)
+}
+
+// processContentFile writes the converted page with its layout
+func (s *Service) processContentFile(p page, cfg Config) error {
+	inputPath, outputPath := p.inputPath, p.outputPath
+	backlinks, err := s.dal.ReadBacklinks(p.md.ID)
 	if err != nil {
 		return err
 	}
+	var data = LayoutData{
+		Content:   template.HTML(p.html),
+		Name:      cfg.Name,
+		Page:      p.md,
+		Authors:   cfg.Authors.ResolveAll(p.md.Authors),
+		Backlinks: backlinks,
+		Related:   p.related,
+		Series:    p.series,
+		Prev:      p.prev,
+		Next:      p.next,
+	}
+	if cfg.cards != nil && data.Page.Image == "" && data.isArticle() {
+		date := ""
+		if !p.md.Created.IsZero() {
+			date = p.md.Created.Format(cfg.DateFormat)
+		}
+		data.Page.Image, err = cfg.cards.Card(p.md.URL, p.md.Title, date)
+		if err != nil {
+			return fmt.Errorf("%s: unable to draw card: %w", inputPath, err)
+		}
+	}
 	ext := filepath.Ext(inputPath) // this should be .md
 	name := outputPath
 	cutoff := len(name) - len(ext)
 	newName := name[0:cutoff] + ".html"
-	filepath.Join()
-	f, err := os.Create(newName)
+	fmt.Println("writing content", inputPath, newName)
+	return writePage(newName, pageLayout(p, cfg), data, cfg)
+}
+
+// writePage executes the named layout of the theme with data and writes the result to path as a read only file
+func writePage(path string, layout string, data LayoutData, cfg Config) error {
+	tmpl, err := cfg.Theme.Layout(layout)
 	if err != nil {
 		return err
 	}
-	fmt.Println("writing content", inputPath, inputPath)
-	_, err = f.WriteString(htmlContentBuilder.String())
+	data.Site = newSite(cfg.Config, cfg.menus, data.Page.URL)
+	var htmlContentBuilder strings.Builder
+	err = tmpl.Execute(&htmlContentBuilder, data)
 	if err != nil {
 		return err
 	}
-	return f.Chmod(0444)
+	content := htmlContentBuilder.String()
+	if cfg.Assets.Minify {
+		content, err = assets.MinifyHTML(content)
+		if err != nil {
+			return fmt.Errorf("unable to minify %s: %w", path, err)
+		}
+	}
+	return fsutil.WriteReadOnly(path, []byte(content))
 }
 
 func (s *Service) clearCompilation(dir string) error {
@@ -154,8 +338,42 @@ func GetOutputPath(inputPath, inputDir, outputDir string) (string, error) {
 	return pandoc.RenameMdToHtml(relPath), nil
 }
 
-func (s *Service) Compilation(contentDir string, outputDir string, staticDir string, cfg Config) error {
-	// create content, converts md to html
+// contentURL is the root relative url that a content file is served at.
+// index files are served at their directory.
+func contentURL(inputPath, contentDir string) (string, error) {
+	op, err := GetOutputPath(inputPath, contentDir, "")
+	if err != nil {
+		return "", err
+	}
+	p := "/" + filepath.ToSlash(op)
+	if path.Base(p) == "index.html" {
+		p = strings.TrimSuffix(p, "index.html")
+	}
+	return (&url.URL{Path: p}).String(), nil
+}
+
+// CheckLinks reports the links in the compiled output that do not resolve to a file in the output directory
+func (s *Service) CheckLinks(contentDir string, outputDir string) ([]check.Problem, error) {
+	files, err := s.dal.ReadMetadataFiles()
+	if err != nil {
+		return nil, err
+	}
+	sources := map[string]string{}
+	for _, f := range files {
+		op, err := GetOutputPath(f, contentDir, outputDir)
+		if err != nil {
+			return nil, err
+		}
+		sources[filepath.ToSlash(op)] = f
+	}
+	return check.Links(outputDir, sources, Routes)
+}
+
+func (s *Service) Compilation(contentDir string, outputDir string, cfg Config) error {
+	// collect the content, mirroring the directory structure in the output dir
+	pages := []page{}
+	sections := []section{}
+	sectionIdx := map[string]int{}
 	walkFunc := func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -166,9 +384,23 @@ func (s *Service) Compilation(contentDir string, outputDir string, staticDir str
 		}
 		destPath := filepath.Join(outputDir, relPath)
 		if info.IsDir() {
+			if relPath != "." {
+				sectionIdx[relPath] = len(sections)
+				sections = append(sections, section{dir: filepath.ToSlash(relPath)})
+			}
 			return os.MkdirAll(destPath, info.Mode())
 		}
-		return s.processContentFile(path, destPath, cfg)
+		if i, ok := sectionIdx[filepath.Dir(relPath)]; ok {
+			if info.Name() == sectionIntroFile {
+				sections[i].introPath = path
+				return nil
+			}
+			if isIndexFile(path) {
+				sections[i].hasIndex = true
+			}
+		}
+		pages = append(pages, page{inputPath: path, outputPath: destPath, dir: filepath.ToSlash(filepath.Dir(relPath))})
+		return nil
 	}
 	err := s.clearCompilation(outputDir)
 	if err != nil {
@@ -184,49 +416,105 @@ func (s *Service) Compilation(contentDir string, outputDir string, staticDir str
 	if err != nil {
 		return err
 	}
-	if _, err := os.Stat(staticDir); os.IsNotExist(err) {
-		static := filepath.Join(outputDir, "static")
-		err := os.MkdirAll(static, 0755)
-		if err != nil {
-			return err
-		}
-		f, err := os.Create(filepath.Join(static, "styles.css"))
-		if err != nil {
-			return err
-		}
-		_, err = f.Write(styles.DefaultCSSStyles)
-		if err != nil {
-			return err
-		}
-		return f.Chmod(0444)
+	if cfg.GitDates && !git.InRepository(contentDir) {
+		return fmt.Errorf("git_dates is enabled but %s is not in a git repository", contentDir)
 	}
-
-	// copy static directory to the output dir under /static
-	walkStaticFunc := func(path string, info os.FileInfo, err error) error {
+	// write the static files first so that layouts can refer to them with asset
+	s.assets, err = assets.Build(cfg.Theme.Static(), outputDir, assets.Options{
+		Minify:      cfg.Assets.Minify,
+		Fingerprint: cfg.Assets.Fingerprint,
+		Bundles:     cfg.Assets.Bundles,
+	})
+	if err != nil {
+		return err
+	}
+	// index all the metadata before converting anything so that content can reference other content
+	for i := range pages {
+		pages[i].md, pages[i].body, pages[i].bodyLine, err = s.updateDB(pages[i].inputPath, contentDir, cfg)
 		if err != nil {
 			return err
 		}
-		relPath, err := filepath.Rel(staticDir, path)
+	}
+	all, err := s.dal.ReadAllMetadata()
+	if err != nil {
+		return err
+	}
+	cfg.menus = menu.Build(cfg.Menus, all)
+	if cfg.Images.Enabled {
+		cfg.images = images.New(images.Options{
+			Widths:   cfg.Images.Widths,
+			Quality:  cfg.Images.Quality,
+			WebP:     cfg.Images.WebP,
+			Sizes:    cfg.Images.Sizes,
+			CacheDir: cfg.Images.CacheDir,
+		}, cfg.Theme.Static(), outputDir)
+	}
+	if cfg.Cards.Enabled {
+		cfg.cards, err = cards.New(cards.Options{
+			SiteName: cfg.Name,
+			Accent:   cfg.Style.AccentColor,
+			CacheDir: cfg.Cards.CacheDir,
+		}, outputDir)
 		if err != nil {
 			return err
 		}
-		destPath := filepath.Join(outputDir, "static", relPath)
-		if info.IsDir() {
-			return os.MkdirAll(destPath, info.Mode())
-		}
-		content, err := os.ReadFile(path)
+	}
+	idx := newWikiIndex(contentDir, all)
+	for i := range pages {
+		err = s.resolveWikiLinks(&pages[i], idx)
 		if err != nil {
 			return err
 		}
-		f, err := os.Create(destPath)
+	}
+	// convert every page before rendering any layout so that layouts (e.g. with pages or .Related) see the summary,
+	// word count and reading time of all the content, whatever order it is compiled in
+	for i := range pages {
+		err = s.convertContent(&pages[i], cfg)
 		if err != nil {
 			return err
 		}
-		_, err = f.Write(content)
+	}
+	all, err = s.dal.ReadAllMetadata()
+	if err != nil {
+		return err
+	}
+	series, err := collectSeries(all)
+	if err != nil {
+		return err
+	}
+	for i := range pages {
+		pages[i].related = related(pages[i].md, all, cfg.Related)
+		pages[i].series, pages[i].prev, pages[i].next = navigation(pages[i].md, all, series)
+	}
+	for _, p := range pages {
+		err = s.processContentFile(p, cfg)
 		if err != nil {
 			return err
 		}
-		return f.Chmod(0444)
 	}
-	return filepath.Walk(staticDir, walkStaticFunc)
+	if cfg.BaseURL == "" {
+		// rss needs absolute links. the feeds are still written so that links to them work
+		fmt.Fprintf(os.Stderr, "warning: base_url is not set, so the links in the feeds are relative and feed readers may reject them\n")
+	}
+	err = writeFeed(outputDir, cfg.Name, "/", feedPosts(all), cfg)
+	if err != nil {
+		return err
+	}
+	err = s.writeSeriesPages(outputDir, series, cfg)
+	if err != nil {
+		return err
+	}
+	err = s.writeSectionPages(outputDir, sections, all, cfg)
+	if err != nil {
+		return err
+	}
+	err = s.writeTagPages(outputDir, all, cfg)
+	if err != nil {
+		return err
+	}
+	err = s.writeArchivePages(outputDir, all, cfg)
+	if err != nil {
+		return err
+	}
+	return s.writeAuthorPages(outputDir, all, cfg)
 }
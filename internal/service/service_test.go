@@ -0,0 +1,45 @@
+package service
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jcocozza/jbf/internal/dal/sqlite"
+	"github.com/jcocozza/jbf/internal/metadata"
+)
+
+// testService is a service with a db of the content, which is given ids in order
+func testService(t *testing.T, content ...metadata.Metadata) *Service {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "jbf.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := sqlite.Schema(db); err != nil {
+		t.Fatal(err)
+	}
+	s := NewService(sqlite.NewSQLiteRepository(db, time.UTC))
+	for i := range content {
+		if err := s.createMetadata(&content[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+// day is midnight on a day of january 2024
+func day(d int) metadata.Date {
+	return metadata.Date(time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC))
+}
+
+// files are the file paths of the content, in order
+func files(ml []metadata.Metadata) []string {
+	f := []string{}
+	for _, m := range ml {
+		f = append(f, m.Filepath)
+	}
+	return f
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"regexp"
+
+	"github.com/jcocozza/jbf/internal/textutil"
+)
+
+// matches the <!--more--> marker that ends the summary of a post
+var moreRegex = regexp.MustCompile(`<!--\s*more\s*-->`)
+
+// summarize returns the text before the <!--more--> marker of the rendered content, or else its first n words
+func summarize(rendered string, n int) string {
+	if loc := moreRegex.FindStringIndex(rendered); loc != nil {
+		return textutil.Plain(rendered[:loc[0]])
+	}
+	return textutil.TruncateWords(textutil.Plain(rendered), n)
+}
+
+// readingTime is the minutes it takes to read words at wpm words per minute, rounded up
+func readingTime(words int, wpm int) int {
+	if words == 0 || wpm <= 0 {
+		return 0
+	}
+	return (words + wpm - 1) / wpm
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jcocozza/jbf/internal/metadata"
+	"github.com/jcocozza/jbf/internal/slug"
+	"github.com/jcocozza/jbf/internal/theme"
+)
+
+const tagsURL = "/tags/"
+
+func tagURL(tag string) string {
+	return tagsURL + slug.Path(tag) + "/"
+}
+
+/*
+groupTags groups the posts by the url of their tags. tags that only differ in case (e.g. Go and go) share a page,
+which is named after the first of them in sorted order. other tags with the same url (e.g. c++ and c) are an error.
+it returns the tag names in sorted order along with the posts of each.
+*/
+func groupTags(all []metadata.Metadata) ([]string, map[string][]metadata.Metadata, error) {
+	tagNames := []string{}
+	for _, m := range all {
+		tagNames = append(tagNames, m.Tags...)
+	}
+	sort.Strings(tagNames)
+	nameByURL := map[string]string{}
+	tags := []string{}
+	for _, tag := range tagNames {
+		name, ok := nameByURL[tagURL(tag)]
+		if !ok {
+			nameByURL[tagURL(tag)] = tag
+			tags = append(tags, tag)
+			continue
+		}
+		if !strings.EqualFold(name, tag) {
+			return nil, nil, fmt.Errorf("tags %q and %q would both be written to %s, rename one of them", name, tag, tagURL(tag))
+		}
+	}
+	byTag := map[string][]metadata.Metadata{}
+	for _, m := range all {
+		seen := map[string]bool{}
+		for _, tag := range m.Tags {
+			name := nameByURL[tagURL(tag)]
+			if !seen[name] {
+				seen[name] = true
+				byTag[name] = append(byTag[name], m)
+			}
+		}
+	}
+	return tags, byTag, nil
+}
+
+// writeTagPages creates a list page for every tag at /tags/<tag>/ and an index of all the tags at /tags/
+func (s *Service) writeTagPages(outputDir string, all []metadata.Metadata, cfg Config) error {
+	tags, byTag, err := groupTags(all)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("<h1>Tags</h1>\n<ul class=\"tags\">\n")
+	for _, tag := range tags {
+		b.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a> (%d)</li>\n", tagURL(tag), template.HTMLEscapeString(tag), len(byTag[tag])))
+	}
+	b.WriteString("</ul>\n")
+	data := LayoutData{
+		Content: template.HTML(b.String()),
+		Name:    cfg.Name,
+		Page:    metadata.Metadata{Title: "Tags", URL: tagsURL},
+	}
+	p := filepath.Join(outputDir, filepath.FromSlash(tagsURL), "index.html")
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	if err := writePage(p, theme.List, data, cfg); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		pageMd := metadata.Metadata{Title: "Tagged " + tag, URL: tagURL(tag)}
+		if err := s.writeList(outputDir, theme.Tag, pageMd, "", byTag[tag], cfg); err != nil {
+			return err
+		}
+		if err := writeFeed(outputDir, cfg.Name+" - "+tag, tagURL(tag), byTag[tag], cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
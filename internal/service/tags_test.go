@@ -0,0 +1,79 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jcocozza/jbf/internal/metadata"
+)
+
+func TestGroupTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		posts    []metadata.Metadata
+		wantTags []string
+		wantBy   map[string][]string
+		wantErr  bool
+	}{
+		{
+			name: "distinct tags",
+			posts: []metadata.Metadata{
+				{Filepath: "a.md", Tags: []string{"go", "web"}},
+				{Filepath: "b.md", Tags: []string{"go"}},
+			},
+			wantTags: []string{"go", "web"},
+			wantBy:   map[string][]string{"go": {"a.md", "b.md"}, "web": {"a.md"}},
+		},
+		{
+			name: "tags that only differ in case share a page",
+			posts: []metadata.Metadata{
+				{Filepath: "a.md", Tags: []string{"go"}},
+				{Filepath: "b.md", Tags: []string{"Go", "go"}},
+			},
+			wantTags: []string{"Go"},
+			wantBy:   map[string][]string{"Go": {"a.md", "b.md"}},
+		},
+		{
+			name: "tags without letters or digits get their own page",
+			posts: []metadata.Metadata{
+				{Filepath: "a.md", Tags: []string{"++", "c"}},
+			},
+			wantTags: []string{"++", "c"},
+			wantBy:   map[string][]string{"++": {"a.md"}, "c": {"a.md"}},
+		},
+		{
+			name: "different tags with the same url",
+			posts: []metadata.Metadata{
+				{Filepath: "a.md", Tags: []string{"c++"}},
+				{Filepath: "b.md", Tags: []string{"c"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags, byTag, err := groupTags(tt.posts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("groupTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !slices.Equal(tags, tt.wantTags) {
+				t.Errorf("groupTags() tags = %v, want %v", tags, tt.wantTags)
+			}
+			if len(byTag) != len(tt.wantBy) {
+				t.Errorf("groupTags() got %d tags, want %d", len(byTag), len(tt.wantBy))
+			}
+			for tag, want := range tt.wantBy {
+				got := []string{}
+				for _, m := range byTag[tag] {
+					got = append(got, m.Filepath)
+				}
+				if !slices.Equal(got, want) {
+					t.Errorf("groupTags() posts of %q = %v, want %v", tag, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jcocozza/jbf/internal/check"
+	"github.com/jcocozza/jbf/internal/metadata"
+)
+
+/*
+match wiki links along with code so that links inside code are left alone
+e.g.
+[[other-post]]
+[[other-post|the text of the link]]
+*/
+var wikiLinkRegex = regexp.MustCompile("(?s)```.*?```|`[^`\n]+`|\\[\\[([^\\]|\n]+)(?:\\|([^\\]\n]+))?\\]\\]")
+
+// wikiIndex resolves the target of a wiki link to content by its path, filename or title
+type wikiIndex struct {
+	byPath     map[string]metadata.Metadata
+	byFilename map[string][]metadata.Metadata
+	byTitle    map[string][]metadata.Metadata
+}
+
+func referenceKey(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func newWikiIndex(contentDir string, ml []metadata.Metadata) wikiIndex {
+	idx := wikiIndex{
+		byPath:     map[string]metadata.Metadata{},
+		byFilename: map[string][]metadata.Metadata{},
+		byTitle:    map[string][]metadata.Metadata{},
+	}
+	for _, m := range ml {
+		rel, err := filepath.Rel(contentDir, m.Filepath)
+		if err == nil {
+			rel = filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+			idx.byPath[referenceKey(rel)] = m
+		}
+		name := filepath.Base(m.Filepath)
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+		idx.byFilename[referenceKey(name)] = append(idx.byFilename[referenceKey(name)], m)
+		if m.Title != "" {
+			idx.byTitle[referenceKey(m.Title)] = append(idx.byTitle[referenceKey(m.Title)], m)
+		}
+	}
+	return idx
+}
+
+// resolve looks the target up by path, then filename, then title
+func (idx wikiIndex) resolve(target string) (metadata.Metadata, error) {
+	key := referenceKey(target)
+	if m, ok := idx.byPath[key]; ok {
+		return m, nil
+	}
+	for _, candidates := range [][]metadata.Metadata{idx.byFilename[key], idx.byTitle[key]} {
+		switch len(candidates) {
+		case 0:
+			continue
+		case 1:
+			return candidates[0], nil
+		default:
+			return metadata.Metadata{}, fmt.Errorf("ambiguous wiki link [[%s]]: matches %s and %s", target, candidates[0].Filepath, candidates[1].Filepath)
+		}
+	}
+	return metadata.Metadata{}, fmt.Errorf("unresolved wiki link [[%s]]", target)
+}
+
+/*
+replaceWikiLinks converts the wiki links in body to markdown links and returns the content they link to.
+links that can't be resolved are left as plain text and returned as problems on the line of body they are on.
+*/
+func replaceWikiLinks(body []byte, idx wikiIndex) ([]byte, []metadata.Metadata, []check.Problem) {
+	var b bytes.Buffer
+	targets := []metadata.Metadata{}
+	problems := []check.Problem{}
+	pos := 0
+	for _, loc := range wikiLinkRegex.FindAllSubmatchIndex(body, -1) {
+		start, end := loc[0], loc[1]
+		b.Write(body[pos:start])
+		pos = end
+		if loc[2] < 0 {
+			// code
+			b.Write(body[start:end])
+			continue
+		}
+		target, text := string(body[loc[2]:loc[3]]), ""
+		if loc[4] >= 0 {
+			text = string(body[loc[4]:loc[5]])
+		}
+		m, err := idx.resolve(target)
+		if err != nil {
+			problems = append(problems, check.Problem{Line: bytes.Count(body[:start], []byte("\n")) + 1, Message: err.Error()})
+			if text == "" {
+				text = target
+			}
+			b.WriteString(text)
+			continue
+		}
+		if text == "" {
+			text = m.Title
+		}
+		targets = append(targets, m)
+		text = strings.NewReplacer("[", `\[`, "]", `\]`).Replace(text)
+		fmt.Fprintf(&b, "[%s](%s)", text, m.URL)
+	}
+	b.Write(body[pos:])
+	return b.Bytes(), targets, problems
+}
+
+// resolveWikiLinks converts the wiki links in the body of p to markdown links and records them for backlinks.
+// links that can't be resolved are reported and left as plain text.
+func (s *Service) resolveWikiLinks(p *page, idx wikiIndex) error {
+	body, targets, problems := replaceWikiLinks(p.body, idx)
+	p.body = body
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stderr, "warning: %s:%d: %s\n", p.inputPath, p.bodyLine+problem.Line-1, problem.Message)
+	}
+	for _, m := range targets {
+		// a page linking to itself is not a backlink
+		if m.Filepath == p.md.Filepath {
+			continue
+		}
+		if err := s.dal.CreateLink(p.md.ID, m.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckWikiLinks reports the wiki links in the content that can't be resolved, or that match more than one file
+func CheckWikiLinks(contentDir string) ([]check.Problem, error) {
+	type file struct {
+		md       metadata.Metadata
+		body     []byte
+		bodyLine int
+	}
+	files := []file{}
+	err := filepath.Walk(contentDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || (info.Name() == sectionIntroFile && filepath.Dir(path) != filepath.Clean(contentDir)) {
+			return nil
+		}
+		// only the paths, titles and bodies are used, so the timezone of the dates doesn't matter
+		md, body, bodyLine, err := metadata.SplitFile(path, time.UTC)
+		if err != nil {
+			// front matter problems are reported by check.Content
+			return nil
+		}
+		files = append(files, file{md: md, body: body, bodyLine: bodyLine})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ml := make([]metadata.Metadata, 0, len(files))
+	for _, f := range files {
+		ml = append(ml, f.md)
+	}
+	idx := newWikiIndex(contentDir, ml)
+	problems := []check.Problem{}
+	for _, f := range files {
+		_, _, found := replaceWikiLinks(f.body, idx)
+		for _, p := range found {
+			problems = append(problems, check.Problem{File: f.md.Filepath, Line: f.bodyLine + p.Line - 1, Message: p.Message})
+		}
+	}
+	check.Sort(problems)
+	return problems, nil
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jcocozza/jbf/internal/check"
+	"github.com/jcocozza/jbf/internal/metadata"
+)
+
+func testWikiContent() []metadata.Metadata {
+	return []metadata.Metadata{
+		{Filepath: "content/blog/first.md", URL: "/blog/first.html", Title: "First Post", Created: day(1)},
+		{Filepath: "content/blog/second.md", URL: "/blog/second.html", Title: "Second", Created: day(2)},
+		{Filepath: "content/notes/second.md", URL: "/notes/second.html", Title: "A note", Created: day(3)},
+		{Filepath: "content/notes/other.md", URL: "/notes/other.html", Title: "Same", Created: day(4)},
+		{Filepath: "content/notes/another.md", URL: "/notes/another.html", Title: "same", Created: day(5)},
+	}
+}
+
+func TestWikiIndexResolve(t *testing.T) {
+	idx := newWikiIndex("content", testWikiContent())
+	tests := []struct {
+		name    string
+		target  string
+		want    string
+		wantErr bool
+	}{
+		{"path", "blog/second", "content/blog/second.md", false},
+		{"filename", "first", "content/blog/first.md", false},
+		{"title", "first post", "content/blog/first.md", false},
+		{"case and spaces", "  FIRST POST ", "content/blog/first.md", false},
+		{"path over ambiguous filename", "notes/second", "content/notes/second.md", false},
+		{"ambiguous filename", "second", "", true},
+		{"ambiguous title", "same", "", true},
+		{"unresolved", "missing", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := idx.resolve(tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolve(%q) error = %v, want error %v", tt.target, err, tt.wantErr)
+			}
+			if m.Filepath != tt.want {
+				t.Errorf("resolve(%q) = %s, want %s", tt.target, m.Filepath, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceWikiLinks(t *testing.T) {
+	idx := newWikiIndex("content", testWikiContent())
+	tests := []struct {
+		name         string
+		body         string
+		want         string
+		wantTargets  []string
+		wantProblems []check.Problem
+	}{
+		{"title as text", "see [[first]]", "see [First Post](/blog/first.html)", []string{"content/blog/first.md"}, []check.Problem{}},
+		{"own text", "[[first|the [1st]]]", `[the \[1st](/blog/first.html)]`, []string{"content/blog/first.md"}, []check.Problem{}},
+		{"two links", "[[first]] [[blog/second|2]]", "[First Post](/blog/first.html) [2](/blog/second.html)", []string{"content/blog/first.md", "content/blog/second.md"}, []check.Problem{}},
+		{"inline code", "`[[first]]`", "`[[first]]`", []string{}, []check.Problem{}},
+		{"fenced code", "```\n[[first]]\n```", "```\n[[first]]\n```", []string{}, []check.Problem{}},
+		{
+			"unresolved", "a\n\n[[missing|the text]] [[missing]]", "a\n\nthe text missing", []string{},
+			[]check.Problem{{Line: 3, Message: "unresolved wiki link [[missing]]"}, {Line: 3, Message: "unresolved wiki link [[missing]]"}},
+		},
+		{
+			"ambiguous", "[[second]]", "second", []string{},
+			[]check.Problem{{Line: 1, Message: "ambiguous wiki link [[second]]: matches content/blog/second.md and content/notes/second.md"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, targets, problems := replaceWikiLinks([]byte(tt.body), idx)
+			if string(got) != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+			if !slices.Equal(files(targets), tt.wantTargets) {
+				t.Errorf("targets = %v, want %v", files(targets), tt.wantTargets)
+			}
+			if !slices.Equal(problems, tt.wantProblems) {
+				t.Errorf("problems = %v, want %v", problems, tt.wantProblems)
+			}
+		})
+	}
+}
+
+func TestBacklinks(t *testing.T) {
+	content := testWikiContent()[:3]
+	s := testService(t, content...)
+	idx := newWikiIndex("content", content)
+	bodies := []string{
+		// a link to itself is not a backlink
+		"[[blog/second]] and [[first]]",
+		"[[first]] twice: [[First Post]]",
+		"[[blog/second]]",
+	}
+	for i, body := range bodies {
+		p := page{inputPath: content[i].Filepath, md: content[i], body: []byte(body), bodyLine: 1}
+		if err := s.resolveWikiLinks(&p, idx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := map[string][]string{
+		"content/blog/first.md":   {"content/blog/second.md"},
+		"content/blog/second.md":  {"content/notes/second.md", "content/blog/first.md"},
+		"content/notes/second.md": {},
+	}
+	for _, m := range content {
+		backlinks, err := s.dal.ReadBacklinks(m.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := files(backlinks); !slices.Equal(got, want[m.Filepath]) {
+			t.Errorf("backlinks of %s = %v, want %v", m.Filepath, got, want[m.Filepath])
+		}
+	}
+}
+
+func TestCheckWikiLinks(t *testing.T) {
+	contentDir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.md":            "---\ntitle: a\n---\n[[b]]\n\n[[missing]]\n",
+		"notes/b.md":      "---\ntitle: b\n---\n[[a]] [[c]]\n",
+		"notes/c.md":      "---\ntitle: c\n---\n",
+		"other/c.md":      "---\ntitle: other c\n---\n",
+		"broken.md":       "no front matter [[missing]]",
+		"notes/_index.md": "---\ntitle: notes\n---\n[[missing]]\n",
+	} {
+		p := filepath.Join(contentDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := CheckWikiLinks(contentDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []check.Problem{
+		{File: filepath.Join(contentDir, "a.md"), Line: 6, Message: "unresolved wiki link [[missing]]"},
+		{File: filepath.Join(contentDir, "notes", "b.md"), Line: 4, Message: "ambiguous wiki link [[c]]: matches " + filepath.Join(contentDir, "notes", "c.md") + " and " + filepath.Join(contentDir, "other", "c.md")},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("CheckWikiLinks() =\n%v\nwant\n%v", got, want)
+	}
+}
@@ -0,0 +1,244 @@
+package shortcode
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jcocozza/jbf/internal/metadata"
+)
+
+//go:embed builtin
+var builtin embed.FS
+
+/*
+match shortcodes along with code so that shortcodes inside code are left alone
+e.g.
+{{< youtube dQw4w9WgXcQ >}}
+{{< figure src="/static/cat.png" caption="a cat" >}}
+{{< note >}}some **markdown**{{< /note >}}
+*/
+var tagRegex = regexp.MustCompile("(?s)```.*?```|`[^`\n]+`|" + `\{\{<\s*(/)?\s*([\w-]+)((?:\s+(?:[\w-]+=)?(?:"(?:[^"\\]|\\.)*"|[^\s">]+))*)\s*>\}\}`)
+
+// an argument of a shortcode, either key=value or a positional value. values with spaces are quoted
+var argRegex = regexp.MustCompile(`(?:([\w-]+)=)?("(?:[^"\\]|\\.)*"|[^\s"]+)`)
+
+// Data is what a shortcode template is executed with
+type Data struct {
+	Name string
+	// the key=value arguments
+	Params map[string]string
+	// the arguments without a key, in order
+	Positional []string
+	// the content between the opening and closing shortcode, as written
+	Inner string
+	// the page the shortcode is in
+	Page metadata.Metadata
+}
+
+// Get returns the positional argument at an index or the named argument with a key, e.g. {{ .Get 0 }} or {{ .Get "src" }}
+func (d Data) Get(key any) string {
+	switch k := key.(type) {
+	case int:
+		if k >= 0 && k < len(d.Positional) {
+			return d.Positional[k]
+		}
+	case string:
+		return d.Params[k]
+	}
+	return ""
+}
+
+// Set is the shortcodes available in content, by name
+type Set struct {
+	templates map[string]*template.Template
+}
+
+/*
+Load reads the built in shortcodes (figure, youtube, gist and note) and the templates in dir, e.g. shortcodes/alert.html is the alert shortcode.
+shortcodes in dir replace built in ones with the same name. A missing dir only has the built in shortcodes.
+*/
+func Load(dir string, funcs template.FuncMap) (*Set, error) {
+	s := &Set{templates: map[string]*template.Template{}}
+	builtinDir, err := fs.Sub(builtin, "builtin")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.load(builtinDir, funcs); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return s, nil
+	}
+	if err := s.load(os.DirFS(dir), funcs); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Set) load(fsys fs.FS, funcs template.FuncMap) error {
+	files, err := fs.Glob(fsys, "*.html")
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		content, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(f, path.Ext(f))
+		tmpl, err := template.New(name).Funcs(funcs).Parse(string(content))
+		if err != nil {
+			return fmt.Errorf("unable to load shortcode %s: %w", name, err)
+		}
+		s.templates[name] = tmpl
+	}
+	return nil
+}
+
+func parseArgs(args string) (map[string]string, []string) {
+	params := map[string]string{}
+	positional := []string{}
+	for _, match := range argRegex.FindAllStringSubmatch(args, -1) {
+		value := match[2]
+		if strings.HasPrefix(value, `"`) {
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			} else {
+				value = strings.Trim(value, `"`)
+			}
+		}
+		if match[1] != "" {
+			params[match[1]] = value
+		} else {
+			positional = append(positional, value)
+		}
+	}
+	return params, positional
+}
+
+// Expand replaces the shortcodes in the markdown body of a page with the output of their templates
+func (s *Set) Expand(body []byte, page metadata.Metadata) ([]byte, error) {
+	out, err := s.expand(string(body), page)
+	return []byte(out), err
+}
+
+// Error is a shortcode error at a line of the body that was expanded. Line is 1 based.
+type Error struct {
+	Line int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// tag is a shortcode or code found in the body
+type tag struct {
+	start, end int
+	code       bool
+	closing    bool
+	name, args string
+	// the index of the closing tag of an opening one, -1 if it has none
+	close int
+}
+
+/*
+scan finds the shortcodes and code in body in one pass and pairs each closing shortcode with the nearest opening one of the same name before it.
+opening shortcodes that are not closed before an enclosing one is take no content, e.g. {{< figure >}} inside a note.
+*/
+func scan(body string) ([]tag, error) {
+	tags := []tag{}
+	// the indexes of the opening tags that are not closed yet
+	open := []int{}
+	for _, loc := range tagRegex.FindAllStringSubmatchIndex(body, -1) {
+		t := tag{start: loc[0], end: loc[1], close: -1}
+		if loc[4] < 0 {
+			t.code = true
+			tags = append(tags, t)
+			continue
+		}
+		t.name = body[loc[4]:loc[5]]
+		t.args = body[loc[6]:loc[7]]
+		t.closing = loc[2] >= 0
+		tags = append(tags, t)
+		if !t.closing {
+			open = append(open, len(tags)-1)
+			continue
+		}
+		matched := false
+		for j := len(open) - 1; j >= 0; j-- {
+			if tags[open[j]].name == t.name {
+				tags[open[j]].close = len(tags) - 1
+				open = open[:j]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, &Error{Line: line(body, t.start), Err: fmt.Errorf("closing shortcode %s without an opening one", t.name)}
+		}
+	}
+	return tags, nil
+}
+
+// line is the 1 based line of body that offset is on
+func line(body string, offset int) int {
+	return strings.Count(body[:offset], "\n") + 1
+}
+
+func (s *Set) expand(body string, page metadata.Metadata) (string, error) {
+	tags, err := scan(body)
+	if err != nil {
+		return "", err
+	}
+	return s.expandTags(body, tags, 0, len(body), 0, len(tags), page)
+}
+
+// expandTags expands body[from:to], which has the tags tags[first:last]
+func (s *Set) expandTags(body string, tags []tag, from, to, first, last int, page metadata.Metadata) (string, error) {
+	var b strings.Builder
+	pos := from
+	for i := first; i < last; i++ {
+		t := tags[i]
+		b.WriteString(body[pos:t.start])
+		pos = t.end
+		if t.code {
+			b.WriteString(body[t.start:t.end])
+			continue
+		}
+		tmpl, ok := s.templates[t.name]
+		if !ok {
+			return "", &Error{Line: line(body, t.start), Err: fmt.Errorf("unknown shortcode %s", t.name)}
+		}
+		data := Data{Name: t.name, Page: page}
+		data.Params, data.Positional = parseArgs(t.args)
+		if t.close >= 0 {
+			closing := tags[t.close]
+			inner, err := s.expandTags(body, tags, t.end, closing.start, i+1, t.close, page)
+			if err != nil {
+				return "", err
+			}
+			data.Inner = inner
+			pos = closing.end
+			i = t.close
+		}
+		var out strings.Builder
+		if err := tmpl.Execute(&out, data); err != nil {
+			return "", err
+		}
+		b.WriteString(out.String())
+	}
+	b.WriteString(body[pos:to])
+	return b.String(), nil
+}
@@ -0,0 +1,102 @@
+package shortcode
+
+import (
+	"errors"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/jcocozza/jbf/internal/metadata"
+)
+
+// testSet has a shortcode that wraps its content and one that prints its arguments
+func testSet(t *testing.T) *Set {
+	t.Helper()
+	funcs := template.FuncMap{"safe": func(s string) template.HTML { return template.HTML(s) }}
+	s := &Set{templates: map[string]*template.Template{}}
+	for name, text := range map[string]string{
+		"b":   `<b>{{ safe .Inner }}</b>`,
+		"i":   `<i>{{ safe .Inner }}</i>`,
+		"arg": `{{ .Get 0 }}|{{ .Get 1 }}|{{ .Get "k" }}`,
+	} {
+		s.templates[name] = template.Must(template.New(name).Funcs(funcs).Parse(text))
+	}
+	return s
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"none", "plain text", "plain text"},
+		{"self closing", "a {{< arg x >}} b", "a x|| b"},
+		{"arguments", `{{< arg x "y z" k="v w" >}}`, "x|y z|v w"},
+		{"escaped quote", `{{< arg "say \"hi\"" >}}`, "say &#34;hi&#34;||"},
+		{"closing", "{{< b >}}bold{{< /b >}}", "<b>bold</b>"},
+		{"spaces in the tags", "{{<b>}}bold{{</ b >}}", "<b>bold</b>"},
+		{"multiline", "{{< b >}}\nbold\n{{< /b >}}", "<b>\nbold\n</b>"},
+		{"nested", "{{< b >}}x {{< i >}}y{{< /i >}} z{{< /b >}}", "<b>x <i>y</i> z</b>"},
+		{"nested with the same name", "{{< b >}}x {{< b >}}y{{< /b >}} z{{< /b >}}", "<b>x <b>y</b> z</b>"},
+		{"siblings", "{{< b >}}x{{< /b >}} {{< b >}}y{{< /b >}}", "<b>x</b> <b>y</b>"},
+		{"self closing inside", "{{< b >}}{{< arg x >}}{{< /b >}}", "<b>x||</b>"},
+		{"unclosed inside", "{{< b >}}{{< i >}}x{{< /b >}}", "<b><i></i>x</b>"},
+		{"inline code", "`{{< nope >}}` {{< arg x >}}", "`{{< nope >}}` x||"},
+		{"fenced code", "```\n{{< nope >}}\n{{< /b >}}\n```\n{{< arg x >}}", "```\n{{< nope >}}\n{{< /b >}}\n```\nx||"},
+		{"code inside", "{{< b >}}`{{< /b >}}`{{< /b >}}", "<b>`{{< /b >}}`</b>"},
+	}
+	s := testSet(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Expand([]byte(tt.body), metadata.Metadata{})
+			if err != nil {
+				t.Fatalf("Expand() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Expand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantLine int
+		wantErr  string
+	}{
+		{"unknown", "a\n\n{{< nope >}}", 3, "unknown shortcode nope"},
+		{"unknown inside", "{{< b >}}\n{{< nope >}}\n{{< /b >}}", 2, "unknown shortcode nope"},
+		{"closing without opening", "a\n{{< /b >}}", 2, "closing shortcode b without an opening one"},
+		{"closing the wrong one", "{{< b >}}\nx{{< /i >}}", 2, "closing shortcode i without an opening one"},
+	}
+	s := testSet(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Expand([]byte(tt.body), metadata.Metadata{})
+			var serr *Error
+			if !errors.As(err, &serr) {
+				t.Fatalf("Expand() error = %v, want an Error", err)
+			}
+			if serr.Line != tt.wantLine || !strings.Contains(serr.Error(), tt.wantErr) {
+				t.Errorf("Expand() error = %v, want line %d: %s", err, tt.wantLine, tt.wantErr)
+			}
+		})
+	}
+}
+
+// a long run of nested shortcodes is matched in one pass
+func TestExpandDeep(t *testing.T) {
+	const depth = 2000
+	body := strings.Repeat("{{< b >}}", depth) + "x" + strings.Repeat("{{< /b >}}", depth)
+	want := strings.Repeat("<b>", depth) + "x" + strings.Repeat("</b>", depth)
+	got, err := testSet(t).Expand([]byte(body), metadata.Metadata{})
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("Expand() of %d nested shortcodes is wrong", depth)
+	}
+}
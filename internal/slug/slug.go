@@ -0,0 +1,38 @@
+package slug
+
+import (
+	"encoding/hex"
+	"strings"
+	"unicode"
+)
+
+// Make lowercases s and replaces everything that isn't a letter or a digit with a single dash
+// e.g. "Hello, World!" -> "hello-world"
+func Make(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
+
+// Path is the slug of s for use in a url. it is never empty: s without any letters or digits is written in hex
+// and an empty s is "_". e.g. "++" -> "2b2b"
+func Path(s string) string {
+	if made := Make(s); made != "" {
+		return made
+	}
+	if s == "" {
+		return "_"
+	}
+	return hex.EncodeToString([]byte(s))
+}
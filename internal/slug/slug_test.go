@@ -0,0 +1,66 @@
+package slug
+
+import "testing"
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Hello, World!", "hello-world"},
+		{"  leading and trailing  ", "leading-and-trailing"},
+		{"a--b__c", "a-b-c"},
+		{"Café Crème", "café-crème"},
+		{"Go 1.23", "go-1-23"},
+		{"++", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := Make(tt.in); got != tt.want {
+				t.Errorf("Make(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Hello, World!", "hello-world"},
+		{"++", "2b2b"},
+		{"#", "23"},
+		{"", "_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := Path(tt.in); got != tt.want {
+				t.Errorf("Path(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// the callers of Path reject names that collide, so these are the collisions they have to catch
+func TestPathCollisions(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		collide bool
+	}{
+		{"Go", "go", true},
+		{"c++", "c", true},
+		{"hello world", "hello-world", true},
+		{"++", "--", false},
+		{"", "_", false},
+		{"c#", "c", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			if got := Path(tt.a) == Path(tt.b); got != tt.collide {
+				t.Errorf("Path(%q) = %q and Path(%q) = %q, want collide %v", tt.a, Path(tt.a), tt.b, Path(tt.b), tt.collide)
+			}
+		})
+	}
+}
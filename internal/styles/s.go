@@ -1,6 +0,0 @@
-package styles
-
-import _ "embed"
-
-//go:embed styles.css
-var DefaultCSSStyles []byte
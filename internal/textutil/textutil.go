@@ -0,0 +1,60 @@
+package textutil
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// tags that separate words, unlike inline tags such as <a> or <em>
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true, "dd": true, "div": true,
+	"dl": true, "dt": true, "figcaption": true, "figure": true, "footer": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true, "li": true, "main": true,
+	"nav": true, "ol": true, "p": true, "pre": true, "section": true, "table": true, "td": true, "th": true,
+	"tr": true, "ul": true,
+}
+
+// Plain returns the text of an html fragment with the tags removed and whitespace collapsed
+func Plain(fragment string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	skip := 0
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			// io.EOF or malformed html, either way return what was read
+			return strings.Join(strings.Fields(b.String()), " ")
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			tt := z.Token()
+			if tt.Data == "script" || tt.Data == "style" {
+				if tt.Type == html.StartTagToken {
+					skip++
+				} else if tt.Type == html.EndTagToken && skip > 0 {
+					skip--
+				}
+			}
+			if blockTags[tt.Data] {
+				b.WriteByte(' ')
+			}
+		case html.TextToken:
+			if skip == 0 {
+				b.Write(z.Text())
+			}
+		}
+	}
+}
+
+// WordCount is the number of whitespace separated words in s
+func WordCount(s string) int {
+	return len(strings.Fields(s))
+}
+
+// TruncateWords returns the first n words of s followed by an ellipsis if anything was cut
+func TruncateWords(s string, n int) string {
+	words := strings.Fields(s)
+	if len(words) <= n {
+		return strings.Join(words, " ")
+	}
+	return strings.Join(words[:n], " ") + "…"
+}
@@ -0,0 +1,66 @@
+package theme
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+// layers is a file system made of other file systems stacked on top of each other.
+// a file is read from the first layer that has it, so earlier layers override later ones file by file.
+type layers []fs.FS
+
+func (l layers) Open(name string) (fs.File, error) {
+	for _, fsys := range l {
+		f, err := fsys.Open(name)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir merges the entries of the directory in every layer
+func (l layers) ReadDir(name string) ([]fs.DirEntry, error) {
+	found := false
+	seen := map[string]bool{}
+	entries := []fs.DirEntry{}
+	for _, fsys := range l {
+		es, err := fs.ReadDir(fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, e := range es {
+			if !seen[e.Name()] {
+				seen[e.Name()] = true
+				entries = append(entries, e)
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// fileFS is a file system with a single file on disk at path, available as name
+type fileFS struct {
+	name string
+	path string
+}
+
+func (f fileFS) Open(name string) (fs.File, error) {
+	if name != f.name {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return os.Open(f.path)
+}
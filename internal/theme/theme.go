@@ -0,0 +1,131 @@
+package theme
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// DefaultName is the theme built into jbf
+const DefaultName string = "default"
+
+// the templates every page kind is rendered with
+const (
+	Single = "single"
+	List   = "list"
+	Tag    = "tag"
+)
+
+// the template that every layout is built on
+const baseFile = "base.html"
+
+//go:embed default
+var builtin embed.FS
+
+/*
+Options says where the theme and the user overrides are.
+
+A theme is a directory with
+
+	layouts/base.html        the page skeleton with a "main" block
+	layouts/single.html      content pages
+	layouts/list.html        list pages (sections, archive, authors, ...)
+	layouts/tag.html         the pages of a tag
+	layouts/partials/*.html  templates shared by the layouts, e.g. header and footer
+	static/                  css and other assets, written to /static/
+*/
+type Options struct {
+	// the name of the theme, a directory in ThemesDir or the built in default
+	Name      string
+	ThemesDir string
+	// templates that override the theme's layouts file by file
+	LayoutsDir string
+	// files that override the theme's static files file by file
+	StaticDir string
+	// a single template file that replaces layouts/base.html
+	BaseLayout string
+	// the functions available in every layout
+	Funcs template.FuncMap
+}
+
+// Theme is the templates and static files a site is built with
+type Theme struct {
+	layouts fs.FS
+	static  fs.FS
+	funcs   template.FuncMap
+	cache   map[string]*template.Template
+}
+
+/*
+Load stacks the user overrides, the named theme and the built in default theme.
+files that are missing from a layer are taken from the one below, so a theme only needs the files it changes.
+*/
+func Load(opts Options) (*Theme, error) {
+	defaultLayouts, err := fs.Sub(builtin, "default/layouts")
+	if err != nil {
+		return nil, err
+	}
+	defaultStatic, err := fs.Sub(builtin, "default/static")
+	if err != nil {
+		return nil, err
+	}
+	layoutLayers := layers{}
+	staticLayers := layers{}
+	if opts.BaseLayout != "" {
+		layoutLayers = append(layoutLayers, fileFS{name: baseFile, path: opts.BaseLayout})
+	}
+	if opts.LayoutsDir != "" {
+		layoutLayers = append(layoutLayers, os.DirFS(opts.LayoutsDir))
+	}
+	if opts.StaticDir != "" {
+		staticLayers = append(staticLayers, os.DirFS(opts.StaticDir))
+	}
+	if opts.Name != "" && opts.Name != DefaultName {
+		dir := filepath.Join(opts.ThemesDir, opts.Name)
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("unable to find theme %s: %w", opts.Name, err)
+		}
+		layoutLayers = append(layoutLayers, os.DirFS(filepath.Join(dir, "layouts")))
+		staticLayers = append(staticLayers, os.DirFS(filepath.Join(dir, "static")))
+	}
+	t := &Theme{
+		layouts: append(layoutLayers, defaultLayouts),
+		static:  append(staticLayers, defaultStatic),
+		funcs:   opts.Funcs,
+		cache:   map[string]*template.Template{},
+	}
+	// parse the layouts up front so that mistakes in them are reported before compiling anything
+	for _, kind := range []string{Single, List, Tag} {
+		if _, err := t.Layout(kind); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Layout is the base template with the partials and the named layout (e.g. single) on top of it
+func (t *Theme) Layout(name string) (*template.Template, error) {
+	if tmpl, ok := t.cache[name]; ok {
+		return tmpl, nil
+	}
+	tmpl, err := template.New(baseFile).Funcs(t.funcs).ParseFS(t.layouts, baseFile, "partials/*.html", name+".html")
+	if err != nil {
+		return nil, fmt.Errorf("unable to load layout %s: %w", name, err)
+	}
+	t.cache[name] = tmpl
+	return tmpl, nil
+}
+
+// Exists reports whether the theme has a layout with the name, e.g. single or blog/single
+func (t *Theme) Exists(name string) bool {
+	_, err := fs.Stat(t.layouts, name+".html")
+	return err == nil
+}
+
+// Static is the static files of the theme, with the user's on top
+func (t *Theme) Static() fs.FS {
+	return t.static
+}